@@ -0,0 +1,83 @@
+package main
+
+import (
+	"cardconjurer-automation/pkg/cardconjurer"
+	"context"
+	"flag"
+	"go.uber.org/zap"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// runCheckSelectors implements the "check-selectors" command: it loads the base url and
+// verifies that every selector of the profile resolves. Returns the exit code.
+func runCheckSelectors(args []string, sugar *zap.SugaredLogger) int {
+	fs := flag.NewFlagSet("check-selectors", flag.ExitOnError)
+	baseUrl := fs.String("base-url", "", "base url")
+	siteDir := fs.String("site-dir", "", "Local Card Conjurer checkout to serve instead of --base-url (optional)")
+	sitePage := fs.String("site-page", cardconjurer.DefaultSitePage, "Path of the creator page within --site-dir")
+	selectorsPath := fs.String("selectors", "", "Selector profile of the Card Conjurer UI (optional, defaults to the embedded profile)")
+	headless := fs.Bool("headless", false, "Run Chrome without a window")
+	chromePath := fs.String("chrome-path", "", "Path to the Chrome executable (optional)")
+	var remoteChrome cardconjurer.StringList
+	fs.Var(&remoteChrome, "remote-chrome", "DevTools endpoint of a running Chrome (ws://... or http://host:9222)")
+	fs.Parse(args)
+
+	selectors, err := loadSelectors(*selectorsPath)
+	if err != nil {
+		sugar.Error(err)
+		return 1
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	cfg := &cardconjurer.Config{
+		Workers:     1,
+		BaseUrl:     *baseUrl,
+		SiteDir:     *siteDir,
+		SitePage:    *sitePage,
+		ProjectName: "check_selectors",
+		Selectors:   selectors,
+		Browser: cardconjurer.BrowserConfig{
+			Headless:   *headless,
+			ExecPath:   *chromePath,
+			RemoteURLs: remoteChrome,
+		},
+	}
+	if err := cfg.Validate(); err != nil {
+		sugar.Error(err)
+		return 1
+	}
+	stopSite, err := cfg.ServeSite(sugar)
+	if err != nil {
+		sugar.Error(err)
+		return 1
+	}
+	defer stopSite()
+
+	check, err := cardconjurer.CheckSite(ctx, cfg, sugar)
+	if err != nil {
+		sugar.Errorf("Selector check failed: %v", err)
+		return 1
+	}
+
+	if check.Version != "" {
+		sugar.Infof("Detected Card Conjurer creator version %s", check.Version)
+	}
+	check.Selectors.WriteTable(os.Stdout)
+	if missing := check.Selectors.Missing(); len(missing) > 0 {
+		sugar.Errorf("%d selector(s) did not resolve on %s, update the selector profile", len(missing), cfg.BaseUrl)
+		return 1
+	}
+	return 0
+}
+
+// loadSelectors reads the selector profile, an empty path uses the embedded default.
+func loadSelectors(path string) (*cardconjurer.Selectors, error) {
+	if path == "" {
+		return nil, nil
+	}
+	return cardconjurer.LoadSelectors(path)
+}
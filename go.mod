@@ -3,12 +3,12 @@ module cardconjurer-automation
 go 1.24.3
 
 require (
+	github.com/chromedp/cdproto v0.0.0-20250521201632-aadd49e0822c
 	github.com/chromedp/chromedp v0.13.6
 	go.uber.org/zap v1.27.0
 )
 
 require (
-	github.com/chromedp/cdproto v0.0.0-20250521201632-aadd49e0822c // indirect
 	github.com/chromedp/sysutil v1.1.0 // indirect
 	github.com/go-json-experiment/json v0.0.0-20250517221953-25912455fbc8 // indirect
 	github.com/gobwas/httphead v0.1.0 // indirect
@@ -7,12 +7,15 @@ import (
 	"cardconjurer-automation/pkg/mpc"
 	"context"
 	"flag"
+	"fmt"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 	"os"
+	"os/signal"
 	"path/filepath"
 	"strings"
 	"sync"
+	"syscall"
 )
 
 func main() {
@@ -23,20 +26,84 @@ func main() {
 	defer logger.Sync()
 	sugar := logger.Sugar()
 
+	if len(os.Args) > 1 && os.Args[1] == "check-selectors" {
+		code := runCheckSelectors(os.Args[2:], sugar)
+		logger.Sync()
+		os.Exit(code)
+	}
+
+	// "refresh-pins" takes the same flags and decklists, but only searches the cards again
+	// and updates their pins in the lock file
+	refreshPins := len(os.Args) > 1 && os.Args[1] == "refresh-pins"
+	if refreshPins {
+		os.Args = append(os.Args[:1], os.Args[2:]...)
+	}
+
 	baseUrl := flag.String("base-url", "", "base url")
+	siteDir := flag.String("site-dir", "", "Local Card Conjurer checkout to serve instead of --base-url (optional)")
+	sitePage := flag.String("site-page", cardconjurer.DefaultSitePage, "Path of the creator page within --site-dir")
 	output := flag.String("output", "", "Path to the output directory for cards")
 	input := flag.String("input", "", "Path to the artwork directory")
 	cardsFilter := flag.String("cards-filter", "", "Card filter (optional, comma separated)")
 	workers := flag.Int("workers", 2, "Number of workers")
+	strict := flag.Bool("strict", false, "Fail if any decklist row is invalid instead of skipping it")
+	naming := flag.String("naming", string(common.NamingPrinting), "Output file naming: \"printing\" (name, set and collector number) or \"name\" (card name only)")
+	pipeline := flag.String("pipeline", strings.Join(cardconjurer.DefaultPipeline, ","), "Comma separated pipeline steps run for every card")
+	frame := flag.String("frame", cardconjurer.DefaultFrame, fmt.Sprintf("Card Conjurer frame, a \"frame\" decklist column overrides it per card (e.g. %s)", strings.Join(cardconjurer.Frames, ", ")))
+	capture := flag.String("capture", cardconjurer.CaptureCanvas, "How rendered cards are saved: \"canvas\" (read from the page, falls back to download) or \"download\"")
+	fallback := flag.String("fallback", string(cardconjurer.FallbackFail), "What to render if Card Conjurer doesn't offer a card's printing: \"fail\", \"any\", \"newest\" or \"preferred\"")
+	matchThreshold := flag.Float64("match-threshold", cardconjurer.DefaultMatchThreshold, "Minimum score (0-100) a Card Conjurer printing needs to match a decklist entry")
+	preferredSets := flag.String("preferred-sets", "", "Comma separated set codes tried in order by --fallback=preferred")
+	maxAttempts := flag.Int("max-attempts", cardconjurer.DefaultRetryPolicy.MaxAttempts, "Attempts per card before it counts as failed")
+	retryBackoff := flag.Duration("retry-backoff", cardconjurer.DefaultRetryPolicy.InitialBackoff, "Wait before the first retry, doubles with every further retry (0 retries right away)")
+	freshBrowserAfter := flag.Int("fresh-browser-after", cardconjurer.DefaultRetryPolicy.FreshBrowserAfter, "Retry a card in a new browser session after this many failed attempts (0 disables)")
+	restartAfter := flag.Int("restart-after", cardconjurer.DefaultRetryPolicy.RestartAfterFailures, "Restart a worker's browser after this many consecutive failures (0 disables)")
+	force := &cardconjurer.ForceSet{}
+	flag.Var(force, "force", "Render cards even if the manifest says they are up to date: --force for all, --force=\"Card A,Card B\" for selected ones (the '=' is required)")
+	shutdownTimeout := flag.Duration("shutdown-timeout", cardconjurer.DefaultShutdownTimeout, "Time cards in progress get to finish after Ctrl+C or SIGTERM")
+	headless := flag.Bool("headless", false, "Run Chrome without a window")
+	chromePath := flag.String("chrome-path", "", "Path to the Chrome executable (optional)")
+	windowSize := flag.String("window-size", "", "Chrome window size, e.g. \"1600x1200\" (optional)")
+	scaleFactor := flag.Float64("scale-factor", 0, "Device scale factor for rendering, e.g. 2 (optional)")
+	userAgent := flag.String("user-agent", "", "User agent of the browser (optional)")
+	var chromeFlags cardconjurer.StringList
+	flag.Var(&chromeFlags, "chrome-flag", "Extra Chrome flag as name or name=value, can be repeated")
+	var remoteChrome cardconjurer.StringList
+	flag.Var(&remoteChrome, "remote-chrome", "DevTools endpoint of a running Chrome (ws://... or http://host:9222), can be repeated to spread workers")
+	selectorsPath := flag.String("selectors", "", "Selector profile of the Card Conjurer UI (optional, defaults to the embedded profile)")
+	scryfallBulk := flag.String("scryfall-bulk", "", "Scryfall bulk data file (e.g. default-cards.json) to answer Card Conjurer's card data requests offline (optional)")
+	scryfallPattern := flag.String("scryfall-pattern", cardconjurer.DefaultScryfallURLPattern, "URL pattern of the Scryfall requests answered from --scryfall-bulk")
+	networkCache := flag.String("network-cache", "", "Directory of the network cache used by --network-cache-mode (optional)")
+	networkCacheMode := flag.String("network-cache-mode", string(cardconjurer.NetworkCachePassthrough), "Network cache mode: \"record\" (store every response), \"replay\" (answer all requests from the cache) or \"passthrough\"")
+	columns := flag.String("columns", "", "CSV column mapping override (optional, e.g. \"count=Qty,name=Card Name,set=Set,number=No\")")
 	flag.Parse()
 
 	if flag.NArg() < 1 {
-		sugar.Error("Error: Path to CSV file must be provided as an argument.")
-		sugar.Info("Usage: ./program [flags] <csv-file>")
+		sugar.Error("Error: Path to decklist file (CSV, Arena/MTGO text or .dek) must be provided as an argument.")
+		sugar.Info("Usage: ./program [flags] <decklist-file|-> [<decklist-file|-> ...]")
+		sugar.Info("       ./program refresh-pins [flags] [--cards-filter \"Card A,Card B\"] <decklist-file|-> [...]")
+		sugar.Info("       ./program check-selectors [--base-url url | --site-dir dir] [--selectors profile.json]")
 		flag.Usage()
 		os.Exit(1)
 	}
-	csvFile := flag.Arg(0)
+	deckFiles := flag.Args()
+	if force.All {
+		// "--force Card A" is parsed as "--force" and a decklist named "Card A"
+		for _, deckFile := range deckFiles {
+			if _, err := os.Stat(deckFile); deckFile != decklist_parser.Stdin && err != nil {
+				sugar.Fatalf("Decklist %q not found, card names are passed as --force=\"%s\"", deckFile, deckFile)
+			}
+		}
+	}
+
+	// The first deck file names the project, stdin ("-") only decks live in the working directory
+	csvFile := "deck"
+	for _, deckFile := range deckFiles {
+		if deckFile != decklist_parser.Stdin {
+			csvFile = deckFile
+			break
+		}
+	}
 
 	projectName := strings.TrimSuffix(filepath.Base(csvFile), filepath.Ext(csvFile))
 	projectName = strings.ToLower(projectName)
@@ -64,16 +131,74 @@ func main() {
 		sugar.Fatalf("Could not create cards folder: %v", err)
 	}
 
-	dp, err := decklist_parser.New(csvFile)
+	windowWidth, windowHeight, err := cardconjurer.ParseWindowSize(*windowSize)
+	if err != nil {
+		sugar.Fatal(err)
+	}
+
+	namingScheme, err := common.ParseNamingScheme(*naming)
+	if err != nil {
+		sugar.Fatal(err)
+	}
+
+	fallbackPolicy, err := cardconjurer.ParseFallbackPolicy(*fallback)
+	if err != nil {
+		sugar.Fatal(err)
+	}
+	cacheMode, err := cardconjurer.ParseNetworkCacheMode(*networkCacheMode)
+	if err != nil {
+		sugar.Fatal(err)
+	}
+
+	var sets []string
+	for _, set := range strings.Split(*preferredSets, ",") {
+		if set = strings.TrimSpace(set); set != "" {
+			sets = append(sets, set)
+		}
+	}
+
+	selectors, err := loadSelectors(*selectorsPath)
 	if err != nil {
 		sugar.Fatal(err)
 	}
 
-	decklist, err := dp.Parse()
+	columnMapping, err := decklist_parser.ParseColumnMapping(*columns)
 	if err != nil {
 		sugar.Fatal(err)
 	}
 
+	var decklists [][]common.CardInfo
+	for _, deckFile := range deckFiles {
+		dp, err := decklist_parser.New(deckFile, &decklist_parser.Config{
+			Columns: columnMapping,
+			Strict:  *strict,
+		})
+		if err != nil {
+			sugar.Fatal(err)
+		}
+
+		decklist, err := dp.Parse()
+		if diagnostics := dp.Diagnostics(); len(diagnostics) > 0 {
+			sugar.Warnf("Skipped %d invalid decklist row(s):\n%s", len(diagnostics), decklist_parser.FormatDiagnostics(diagnostics))
+		}
+		if err != nil {
+			sugar.Fatal(err)
+		}
+		decklists = append(decklists, decklist)
+	}
+
+	decklist, conflicts := decklist_parser.Merge(decklists...)
+	if len(conflicts) > 0 {
+		sugar.Warnf("Found %d conflicting value(s) in merged decklist entries:\n%s", len(conflicts), decklist_parser.FormatDiagnostics(conflicts))
+		if *strict {
+			sugar.Fatal(&decklist_parser.ParseError{Diagnostics: conflicts})
+		}
+	}
+	sugar.Infof("Normalized decklist has %d entries:", len(decklist))
+	for _, card := range decklist {
+		sugar.Infow(card.GetFullName(), "count", card.GetCount(), "sources", card.GetSources())
+	}
+
 	sugar.Infof("card filter: %s", *cardsFilter)
 
 	var cardList []common.CardInfo
@@ -94,14 +219,56 @@ func main() {
 	}
 
 	wg := &sync.WaitGroup{}
-	ctx := context.Background()
+	// Ctrl+C and SIGTERM stop rendering new cards and let the ones in progress finish
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		// Restore the default behavior once the first signal arrived, so a second one kills the process
+		stop()
+	}()
 
 	ccCfg := &cardconjurer.Config{
 		Workers:            *workers,
 		BaseUrl:            *baseUrl,
+		SiteDir:            *siteDir,
+		SitePage:           *sitePage,
 		InputArtworkFolder: *input,
 		OutputCardsFolder:  *output,
 		ProjectName:        projectName,
+		Naming:             namingScheme,
+		Frame:              *frame,
+		CaptureMode:        *capture,
+		Fallback:           fallbackPolicy,
+		PreferredSets:      sets,
+		MatchThreshold:     *matchThreshold,
+		Pipeline:           cardconjurer.ParsePipeline(*pipeline),
+		ManifestPath:       filepath.Join(filepath.Dir(csvFile), fmt.Sprintf("%s_manifest.json", projectName)),
+		LockPath:           filepath.Join(filepath.Dir(csvFile), cardconjurer.LockFileName),
+		Force:              force,
+		ShutdownTimeout:    *shutdownTimeout,
+		Selectors:          selectors,
+		ScryfallBulkPath:   *scryfallBulk,
+		ScryfallURLPattern: *scryfallPattern,
+		NetworkCacheDir:    *networkCache,
+		NetworkCacheMode:   cacheMode,
+		Browser: cardconjurer.BrowserConfig{
+			Headless:          *headless,
+			ExecPath:          *chromePath,
+			WindowWidth:       windowWidth,
+			WindowHeight:      windowHeight,
+			DeviceScaleFactor: *scaleFactor,
+			ExtraFlags:        chromeFlags,
+			UserAgent:         *userAgent,
+			RemoteURLs:        remoteChrome,
+		},
+		Retry: cardconjurer.RetryPolicy{
+			MaxAttempts:          *maxAttempts,
+			InitialBackoff:       *retryBackoff,
+			MaxBackoff:           cardconjurer.DefaultRetryPolicy.MaxBackoff,
+			FreshBrowserAfter:    *freshBrowserAfter,
+			RestartAfterFailures: *restartAfter,
+		},
 	}
 
 	cc, err := cardconjurer.New(ccCfg, sugar, cardList)
@@ -109,23 +276,59 @@ func main() {
 		sugar.Fatal(err)
 	}
 
+	if refreshPins {
+		err := cc.RefreshPins(ctx)
+		if err != nil {
+			sugar.Error(err)
+		} else {
+			sugar.Infof("Pins written to %s", ccCfg.LockPath)
+		}
+		report := cc.Report()
+		report.WriteTable(os.Stdout)
+		if err != nil || report.Failed > 0 || ctx.Err() != nil {
+			logger.Sync()
+			os.Exit(1)
+		}
+		return
+	}
+
+	var runErr error
 	wg.Add(2)
 	go func() {
 		defer wg.Done()
-		cc.Run(ctx)
+		runErr = cc.Run(ctx)
 	}()
 
 	mpcCfg := &mpc.Config{
 		ProjectPath: filepath.Dir(csvFile),
 		ProjectName: projectName,
+		Naming:      namingScheme,
 	}
 
 	mpc := mpc.New(mpcCfg, sugar)
 
 	go func() {
 		defer wg.Done()
-		mpc.Run(cc.GetOutputChan(), ctx)
+		mpc.Run(cc.GetOutputChan())
 	}()
 
 	wg.Wait()
+
+	if runErr != nil {
+		sugar.Error(runErr)
+	}
+
+	report := cc.Report()
+	reportPath := filepath.Join(filepath.Dir(csvFile), fmt.Sprintf("%s_report.json", projectName))
+	if err := report.Save(reportPath); err != nil {
+		sugar.Errorf("Could not write run report: %v", err)
+	} else {
+		sugar.Infof("Run report written to %s", reportPath)
+	}
+	report.WriteTable(os.Stdout)
+
+	if runErr != nil || report.Failed > 0 || ctx.Err() != nil {
+		logger.Sync()
+		os.Exit(1)
+	}
 }
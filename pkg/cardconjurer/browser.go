@@ -2,54 +2,115 @@ package cardconjurer
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"github.com/chromedp/cdproto/browser"
 	"github.com/chromedp/chromedp"
 	"os"
-	"time"
+	"path/filepath"
 )
 
 func (w *worker) openBrowser(parentCtx context.Context) (context.Context, error) {
 	w.logger.Info("Starting new browser session")
+
+	var taskCtx context.Context
+	var err error
+	if remote := w.config.Browser.remoteURL(w.workerID); remote != "" {
+		taskCtx, err = w.connectRemoteBrowser(parentCtx, remote)
+	} else {
+		taskCtx, err = w.launchLocalBrowser(parentCtx)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	actions := []chromedp.Action{}
+	if w.downloadDir != "" {
+		// Downloads are named by their GUID and reported through download events
+		actions = append(actions, browser.SetDownloadBehavior(browser.SetDownloadBehaviorBehaviorAllowAndName).
+			WithDownloadPath(w.downloadDir).
+			WithEventsEnabled(true))
+	}
+	if handlers := w.requestHandlers(); len(handlers) > 0 {
+		actions = append(actions, w.interceptRequests(taskCtx, handlers))
+	}
+	actions = append(actions,
+		chromedp.Navigate(w.config.BaseUrl),
+		// Wait until the document is fully loaded
+		chromedp.WaitReady("body"),
+		chromedp.Sleep(w.config.selectors().pageLoadDelay()),
+	)
+
+	w.logger.Infof("Opening browser at %s and sleeping for %s", w.config.BaseUrl, w.config.selectors().pageLoadDelay())
+	if err := chromedp.Run(taskCtx, actions...); err != nil {
+		// chromedp.Cancel would wait for a browser that possibly never started
+		w.cancelBrowser()
+		w.cancelBrowser = nil
+		w.removeProfileDir()
+		return nil, err
+	}
+
+	return taskCtx, nil
+}
+
+// launchLocalBrowser starts a new Chrome process with its own temporary profile.
+func (w *worker) launchLocalBrowser(parentCtx context.Context) (context.Context, error) {
 	w.logger.Infof("Creating temp directory for worker %d: %s", w.workerID, w.tempDirName)
 	dir, err := os.MkdirTemp("", w.tempDirName)
 	if err != nil {
 		w.logger.Errorf("Error creating temp directory: %v", err)
 		return nil, err
 	}
-	// os.RemoveAll(dir) should be handled by the caller
+	// os.RemoveAll(dir) is handled by closeBrowser
+	w.profileDir = dir
 
-	// Explicitly set the download folder to the desired OutputCardsFolder
-	downloadDir := w.config.OutputCardsFolder
-	if downloadDir == "" {
-		downloadDir = dir // fallback
+	// Every worker downloads into its own folder, so workers can't pick up each other's files
+	w.downloadDir = filepath.Join(dir, "downloads")
+	if err := os.MkdirAll(w.downloadDir, 0755); err != nil {
+		w.logger.Errorf("Error creating download directory: %v", err)
+		w.removeProfileDir()
+		return nil, err
 	}
 
-	w.logger.Infof("Download directory: %s", downloadDir)
+	w.logger.Infof("Download directory: %s", w.downloadDir)
 
-	opts := append(chromedp.DefaultExecAllocatorOptions[:],
-		chromedp.DisableGPU,
-		chromedp.UserDataDir(dir),
-		chromedp.Flag("headless", false),
-		chromedp.Flag("download.default_directory", downloadDir),
-		chromedp.Flag("download.prompt_for_download", false),
-	)
+	allocCtx, cancel := chromedp.NewExecAllocator(parentCtx, w.config.Browser.allocatorOptions(dir)...)
+	taskCtx, cancel2 := chromedp.NewContext(allocCtx, chromedp.WithLogf(w.logger.Infof))
+	// Both are cancelled by closeBrowser
+	w.cancelBrowser = func() {
+		cancel2()
+		cancel()
+	}
 
-	allocCtx, cancel := chromedp.NewExecAllocator(parentCtx, opts...)
-	// cancel should be handled by the caller
+	return taskCtx, nil
+}
 
-	taskCtx, cancel2 := chromedp.NewContext(allocCtx, chromedp.WithLogf(w.logger.Infof))
-	// cancel2 should be handled by the caller
+// connectRemoteBrowser opens a tab in an already running Chrome. The tab lives in its own
+// browser context, so workers sharing a browser don't share cookies or state.
+// Downloads are not available, as they would end up on the remote filesystem.
+func (w *worker) connectRemoteBrowser(parentCtx context.Context, remoteURL string) (context.Context, error) {
+	w.logger.Infof("Connecting to remote browser at %s", remoteURL)
 
-	w.logger.Infof("Opening browser at %s and sleeping for two seconds", w.config.BaseUrl)
-	if err := chromedp.Run(taskCtx,
-		chromedp.Navigate(w.config.BaseUrl),
-		// Wait until the document is fully loaded
-		chromedp.WaitReady("body"),
-		chromedp.Sleep(2*time.Second),
-	); err != nil {
+	allocCtx, cancel := chromedp.NewRemoteAllocator(parentCtx, remoteURL)
+	connCtx, cancel2 := chromedp.NewContext(allocCtx, chromedp.WithLogf(w.logger.Infof))
+	// Closing the connection context only drops the websocket, the remote browser keeps running
+	w.cancelBrowser = func() {
 		cancel2()
 		cancel()
-		return nil, err
+	}
+
+	// Connect first, the first context attaches to an existing tab of the browser
+	if err := chromedp.Run(connCtx); err != nil {
+		w.cancelBrowser()
+		w.cancelBrowser = nil
+		return nil, fmt.Errorf("error connecting to remote browser %s: %v", remoteURL, err)
+	}
+
+	taskCtx, cancel3 := chromedp.NewContext(connCtx, chromedp.WithNewBrowserContext())
+	cancelConn := w.cancelBrowser
+	w.cancelBrowser = func() {
+		cancel3()
+		cancelConn()
 	}
 
 	return taskCtx, nil
@@ -57,33 +118,48 @@ func (w *worker) openBrowser(parentCtx context.Context) (context.Context, error)
 
 func (w *worker) closeBrowser(browserCtx context.Context) {
 	w.logger.Info("Closing browser session")
-	if err := chromedp.Cancel(browserCtx); err != nil {
+	// Cancel waits until a local Chrome has exited, so the profile is no longer in use
+	// afterwards. For remote browsers it only closes the worker's tab.
+	if err := chromedp.Cancel(browserCtx); err != nil && !errors.Is(err, context.Canceled) {
 		w.logger.Errorf("Error closing browser: %v", err)
 	}
+	if w.cancelBrowser != nil {
+		w.cancelBrowser()
+		w.cancelBrowser = nil
+	}
 	w.logger.Info("Browser closed")
-	// Delete temp directory
-	if w.tempDirName != "" {
-		if err := os.RemoveAll(w.tempDirName); err != nil {
-			w.logger.Errorf("Error deleting temp directory %s: %v", w.tempDirName, err)
-		} else {
-			w.logger.Infof("Temp directory deleted: %s", w.tempDirName)
-		}
+	w.removeProfileDir()
+}
+
+// removeProfileDir deletes the temp directory of the browser session.
+func (w *worker) removeProfileDir() {
+	if w.profileDir == "" {
+		return
+	}
+	if err := os.RemoveAll(w.profileDir); err != nil {
+		w.logger.Errorf("Error deleting temp directory %s: %v", w.profileDir, err)
+	} else {
+		w.logger.Infof("Temp directory deleted: %s", w.profileDir)
 	}
+	w.profileDir = ""
+	w.downloadDir = ""
 }
 
 // openTab opens a tab by its name (e.g. "import", "frame").
 // It can wait for any number of selectors after the click.
 func (w *worker) openTab(ctx context.Context, tabName string, waitForSelectors ...string) error {
-	selector := fmt.Sprintf(`h3.selectable.readable-background[onclick*="toggleCreatorTabs"][onclick*="%s"]`, tabName)
+	selector := w.config.selectors().tab(tabName)
 	w.logger.Infof("Opening tab: %s", tabName)
-	actions := []chromedp.Action{
-		chromedp.Click(selector),
+	if err := w.driver.Click(ctx, selector); err != nil {
+		return err
 	}
 	for _, sel := range waitForSelectors {
 		if sel != "" {
 			w.logger.Infof("Waiting for element after tab switch: %s", sel)
-			actions = append(actions, chromedp.WaitVisible(sel))
+			if err := w.driver.WaitVisible(ctx, sel); err != nil {
+				return err
+			}
 		}
 	}
-	return chromedp.Run(ctx, actions...)
+	return nil
 }
@@ -0,0 +1,144 @@
+package cardconjurer
+
+import (
+	"errors"
+	"fmt"
+	"github.com/chromedp/chromedp"
+	"net/url"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// BrowserConfig controls how the Chrome instances of the workers are launched.
+type BrowserConfig struct {
+	Headless bool
+	// ExecPath is the Chrome executable, empty to let chromedp look it up
+	ExecPath string
+	// WindowWidth and WindowHeight are the window size in pixels, 0 keeps Chrome's default
+	WindowWidth  int
+	WindowHeight int
+	// DeviceScaleFactor scales the rendering resolution, 0 keeps Chrome's default
+	DeviceScaleFactor float64
+	// ExtraFlags are additional Chrome flags as "name" or "name=value", with or without leading dashes
+	ExtraFlags []string
+	UserAgent  string
+	// RemoteURLs are DevTools endpoints (ws://... or http://host:port) of running Chrome
+	// instances. If set, workers are spread across them instead of launching Chrome.
+	RemoteURLs []string
+}
+
+// remoteURL returns the DevTools endpoint of the worker, "" to launch a local browser.
+func (b *BrowserConfig) remoteURL(workerID int) string {
+	if len(b.RemoteURLs) == 0 {
+		return ""
+	}
+	return b.RemoteURLs[workerID%len(b.RemoteURLs)]
+}
+
+// ParseWindowSize parses a size like "1600x1200".
+func ParseWindowSize(s string) (int, int, error) {
+	if strings.TrimSpace(s) == "" {
+		return 0, 0, nil
+	}
+
+	width, height, ok := strings.Cut(strings.ToLower(s), "x")
+	if !ok {
+		return 0, 0, fmt.Errorf("invalid window size %q, expected <width>x<height>", s)
+	}
+	w, errW := strconv.Atoi(strings.TrimSpace(width))
+	h, errH := strconv.Atoi(strings.TrimSpace(height))
+	if errW != nil || errH != nil {
+		return 0, 0, fmt.Errorf("invalid window size %q, expected <width>x<height>", s)
+	}
+	return w, h, nil
+}
+
+func (b *BrowserConfig) Validate() error {
+	if b.ExecPath != "" {
+		if _, err := exec.LookPath(b.ExecPath); err != nil {
+			return fmt.Errorf("chrome executable %q is not usable: %v", b.ExecPath, err)
+		}
+	}
+
+	if b.WindowWidth < 0 || b.WindowHeight < 0 || (b.WindowWidth == 0) != (b.WindowHeight == 0) {
+		return fmt.Errorf("invalid window size %dx%d, width and height must both be positive", b.WindowWidth, b.WindowHeight)
+	}
+
+	if b.DeviceScaleFactor < 0 || b.DeviceScaleFactor > 10 {
+		return fmt.Errorf("invalid device scale factor %g, expected a value between 0 and 10", b.DeviceScaleFactor)
+	}
+
+	if strings.ContainsAny(b.UserAgent, "\r\n") {
+		return errors.New("user agent must not contain line breaks")
+	}
+
+	for _, flag := range b.ExtraFlags {
+		name, _ := parseChromeFlag(flag)
+		if name == "" || strings.ContainsAny(name, " \t") {
+			return fmt.Errorf("invalid chrome flag %q", flag)
+		}
+	}
+
+	for _, remote := range b.RemoteURLs {
+		u, err := url.Parse(remote)
+		if err != nil || u.Host == "" {
+			return fmt.Errorf("invalid DevTools endpoint %q", remote)
+		}
+		switch u.Scheme {
+		case "ws", "wss", "http", "https":
+		default:
+			return fmt.Errorf("invalid DevTools endpoint %q, expected a ws:// or http:// URL", remote)
+		}
+	}
+
+	return nil
+}
+
+func parseChromeFlag(flag string) (string, interface{}) {
+	flag = strings.TrimLeft(strings.TrimSpace(flag), "-")
+	if name, value, ok := strings.Cut(flag, "="); ok {
+		return name, value
+	}
+	return flag, true
+}
+
+// allocatorOptions returns the chromedp options for a browser using the given profile directory.
+func (b *BrowserConfig) allocatorOptions(profileDir string) []chromedp.ExecAllocatorOption {
+	opts := append(chromedp.DefaultExecAllocatorOptions[:],
+		chromedp.DisableGPU,
+		chromedp.UserDataDir(profileDir),
+		chromedp.Flag("headless", b.Headless),
+	)
+
+	if b.ExecPath != "" {
+		opts = append(opts, chromedp.ExecPath(b.ExecPath))
+	}
+	if b.WindowWidth > 0 && b.WindowHeight > 0 {
+		opts = append(opts, chromedp.WindowSize(b.WindowWidth, b.WindowHeight))
+	}
+	if b.DeviceScaleFactor > 0 {
+		opts = append(opts, chromedp.Flag("force-device-scale-factor", strconv.FormatFloat(b.DeviceScaleFactor, 'f', -1, 64)))
+	}
+	if b.UserAgent != "" {
+		opts = append(opts, chromedp.UserAgent(b.UserAgent))
+	}
+	for _, flag := range b.ExtraFlags {
+		name, value := parseChromeFlag(flag)
+		opts = append(opts, chromedp.Flag(name, value))
+	}
+
+	return opts
+}
+
+// StringList is a flag.Value collecting repeated flags.
+type StringList []string
+
+func (l *StringList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *StringList) Set(s string) error {
+	*l = append(*l, s)
+	return nil
+}
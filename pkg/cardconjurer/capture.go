@@ -0,0 +1,54 @@
+package cardconjurer
+
+import (
+	"bytes"
+	"cardconjurer-automation/pkg/common"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Ways to get the rendered card out of the browser
+const (
+	// CaptureCanvas reads the card straight from the canvas and falls back to CaptureDownload
+	CaptureCanvas = "canvas"
+	// CaptureDownload clicks Card Conjurer's download button and picks up the downloaded file
+	CaptureDownload = "download"
+)
+
+var pngSignature = []byte("\x89PNG\r\n\x1a\n")
+
+// captureCanvas reads the rendered card as PNG from the page and writes it to the target path.
+// Card Conjurer renders the full size card into a global canvas variable. Without it an
+// error is returned, so the card is downloaded instead of saving the half size preview.
+func (w *worker) captureCanvas(targetPath string, browserCtx context.Context) error {
+	var dataURL string
+	if err := w.driver.Evaluate(browserCtx, fmt.Sprintf(`(() => {
+			const c = window[%s];
+			return c instanceof HTMLCanvasElement ? c.toDataURL('image/png') : '';
+		})()`, jsString(w.config.selectors().CardCanvasVariable)), &dataURL); err != nil {
+		return err
+	}
+
+	const prefix = "data:image/png;base64,"
+	if !strings.HasPrefix(dataURL, prefix) {
+		return fmt.Errorf("no card canvas %q found on the page", w.config.selectors().CardCanvasVariable)
+	}
+
+	data, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(dataURL, prefix))
+	if err != nil {
+		return fmt.Errorf("error decoding canvas data: %v", err)
+	}
+	if !bytes.HasPrefix(data, pngSignature) {
+		return errors.New("canvas data is not a PNG image")
+	}
+
+	if err := common.WriteFileAtomic(targetPath, data, 0644); err != nil {
+		return fmt.Errorf("error writing card image: %v", err)
+	}
+
+	w.logger.Infof("Card captured from canvas: %s (%d bytes)", targetPath, len(data))
+	return nil
+}
@@ -4,8 +4,11 @@ import (
 	"cardconjurer-automation/pkg/common"
 	"context"
 	"errors"
+	"fmt"
 	"go.uber.org/zap"
+	"slices"
 	"sync"
+	"time"
 )
 
 type CardConjurer struct {
@@ -13,7 +16,18 @@ type CardConjurer struct {
 	cards      []common.CardInfo
 	cardsChan  chan common.CardInfo
 	outputChan chan common.CardInfo
+	pipeline   []step
+	manifest   *Manifest
+	lock       *LockFile
+	scryfall   *ScryfallData
+	cache      *NetworkCache
 	logger     *zap.SugaredLogger
+
+	resultsMu   sync.Mutex
+	results     map[common.CardInfo]CardReport
+	resolutions map[common.CardInfo]resolution
+	started     time.Time
+	finished    time.Time
 }
 
 func New(cfg *Config, logger *zap.SugaredLogger, cards []common.CardInfo) (*CardConjurer, error) {
@@ -22,11 +36,68 @@ func New(cfg *Config, logger *zap.SugaredLogger, cards []common.CardInfo) (*Card
 		return nil, errors.New("config is nil")
 	}
 
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	if err := cfg.Retry.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid retry policy: %v", err)
+	}
+
+	pipeline, err := buildPipeline(cfg.Pipeline)
+	if err != nil {
+		return nil, err
+	}
+	if len(cfg.Pipeline) > 0 && !slices.Contains(cfg.Pipeline, StepSave) {
+		logger.Warn("The pipeline has no save step, no card files will be written")
+	}
+
+	var manifest *Manifest
+	if cfg.ManifestPath != "" {
+		manifest, err = LoadManifest(cfg.ManifestPath)
+		if err != nil {
+			return nil, fmt.Errorf("error reading manifest %s: %v", cfg.ManifestPath, err)
+		}
+	}
+
+	var lock *LockFile
+	if cfg.LockPath != "" {
+		lock, err = LoadLockFile(cfg.LockPath)
+		if err != nil {
+			return nil, fmt.Errorf("error reading lock file %s: %v", cfg.LockPath, err)
+		}
+	}
+
+	var scryfall *ScryfallData
+	if cfg.ScryfallBulkPath != "" {
+		scryfall, err = LoadScryfallData(cfg.ScryfallBulkPath)
+		if err != nil {
+			return nil, fmt.Errorf("error reading Scryfall bulk data %s: %v", cfg.ScryfallBulkPath, err)
+		}
+		logger.Infof("Offline mode, answering Scryfall requests from %d cards in %s", scryfall.Len(), cfg.ScryfallBulkPath)
+	}
+
+	var cache *NetworkCache
+	if cfg.NetworkCacheMode == NetworkCacheRecord || cfg.NetworkCacheMode == NetworkCacheReplay {
+		cache, err = LoadNetworkCache(cfg.NetworkCacheDir, cfg.NetworkCacheMode)
+		if err != nil {
+			return nil, fmt.Errorf("error reading network cache %s: %v", cfg.NetworkCacheDir, err)
+		}
+		logger.Infof("Network cache in %s mode, %d recorded request(s) in %s", cfg.NetworkCacheMode, cache.Len(), cfg.NetworkCacheDir)
+	}
+
 	return &CardConjurer{
-		config:     cfg,
-		cards:      cards,
-		outputChan: make(chan common.CardInfo, 1000),
-		logger:     logger,
+		config:      cfg,
+		cards:       cards,
+		outputChan:  make(chan common.CardInfo, 1000),
+		pipeline:    pipeline,
+		manifest:    manifest,
+		lock:        lock,
+		scryfall:    scryfall,
+		cache:       cache,
+		results:     make(map[common.CardInfo]CardReport),
+		resolutions: make(map[common.CardInfo]resolution),
+		logger:      logger,
 	}, nil
 }
 
@@ -40,46 +111,259 @@ func (cc *CardConjurer) ListCards() {
 	}
 }
 
-func (cc *CardConjurer) Run(ctx context.Context) {
+// Run renders all cards and sends the rendered ones to the output channel, which is
+// always closed when Run returns. After ctx is cancelled no new cards are started,
+// cards in progress get Config.ShutdownTimeout to finish before the browsers are closed.
+// Before any worker is started the site is checked, a *PreflightError is returned if
+// it does not provide the expected UI. Cards that failed are reported by Report.
+func (cc *CardConjurer) Run(ctx context.Context) error {
+	cc.started = time.Now()
+	defer func() {
+		cc.finished = time.Now()
+	}()
+	defer close(cc.outputChan)
+
+	stopSite, err := cc.config.ServeSite(cc.logger)
+	if err != nil {
+		return err
+	}
+	defer stopSite()
+	defer cc.saveCache()
+
+	// Up to date cards go straight to the output, the others are resolved before rendering
+	var pending []common.CardInfo
+	upToDate := make(map[common.CardInfo]ManifestEntry)
+	for _, card := range cc.cards {
+		if entry, ok := cc.upToDate(card); ok {
+			upToDate[card] = entry
+		} else {
+			pending = append(pending, card)
+		}
+	}
+
+	// Nothing to render needs no browser
+	if len(pending) > 0 {
+		if err := cc.preflight(ctx, pending); err != nil {
+			return err
+		}
+	}
+
+	for _, card := range cc.cards {
+		if ctx.Err() != nil {
+			break
+		}
+		if entry, ok := upToDate[card]; ok {
+			cc.skipUpToDate(card, entry)
+		}
+	}
+
+	// Browsers outlive ctx, so they are only torn down after the grace period
+	browserCtx, cancelBrowsers := context.WithCancel(context.WithoutCancel(ctx))
+	defer cancelBrowsers()
+	go cc.shutdownAfterGracePeriod(ctx, browserCtx, cancelBrowsers)
+
+	// The browsers opened to resolve the cards are used for rendering afterwards
+	workers := make([]*worker, cc.config.Workers)
+	for i := range workers {
+		workers[i] = cc.newWorker(i, cc.logger, cc.pipeline)
+		workers[i].onResult = cc.addResult
+		workers[i].resolutions = cc.resolutions
+	}
+	if len(pending) > 0 && slices.ContainsFunc(cc.pipeline, func(s step) bool { return s.name == StepImport }) {
+		pending = cc.resolveCards(ctx, browserCtx, workers, pending, true)
+	}
+
 	var wg sync.WaitGroup
 	cc.cardsChan = make(chan common.CardInfo, cc.config.Workers)
 
 	cc.logger.Infof("Starting %d worker(s)", cc.config.Workers)
 	// Start workers
-	for i := 0; i < cc.config.Workers; i++ {
+	for _, w := range workers {
 		wg.Add(1)
-		go cc.startWorker(i, ctx, &wg)
+		go cc.startWorker(w, ctx, browserCtx, &wg)
 	}
 
-	cc.logger.Infof("Sending %d cards to workers", len(cc.cards))
+	cc.logger.Infof("Sending %d cards to workers", len(pending))
 	// Send cards to the channel
-	for _, card := range cc.cards {
+dispatch:
+	for _, card := range pending {
 		select {
 		case <-ctx.Done():
-			cc.logger.Info("Context cancelled, closing cardsChan and waiting for workers")
-			close(cc.cardsChan)
-			wg.Wait()
-			cc.logger.Info("All workers finished.")
-			return
+			break dispatch
 		case cc.cardsChan <- card:
 			cc.logger.Infof("Card '%s' sent to worker.", card.GetFullName())
 		}
 	}
-	cc.logger.Info("All cards have been sent to workers. Closing cardsChan.")
+
+	if ctx.Err() != nil {
+		cc.logger.Info("Context cancelled, no more cards are sent to workers")
+	} else {
+		cc.logger.Info("All cards have been sent to workers. Closing cardsChan.")
+	}
 	close(cc.cardsChan)
 	wg.Wait()
-	close(cc.outputChan)
 	cc.logger.Info("All workers have finished their work.")
+	return nil
 }
 
-func (cc *CardConjurer) startWorker(id int, ctx context.Context, wg *sync.WaitGroup) {
-	cc.logger.Infof("Starting worker %d", id)
+// shutdownAfterGracePeriod closes all browsers once ctx is cancelled and the shutdown timeout passed.
+func (cc *CardConjurer) shutdownAfterGracePeriod(ctx, browserCtx context.Context, cancelBrowsers context.CancelFunc) {
+	select {
+	case <-browserCtx.Done():
+		return
+	case <-ctx.Done():
+	}
+
+	timeout := cc.config.ShutdownTimeout
+	if timeout <= 0 {
+		timeout = DefaultShutdownTimeout
+	}
+	cc.logger.Infof("Shutting down, cards in progress have %s to finish", timeout)
+
+	select {
+	case <-browserCtx.Done():
+	case <-time.After(timeout):
+		cc.logger.Warn("Shutdown timeout reached, closing browsers")
+		cancelBrowsers()
+	}
+}
+
+// cardInputs collects everything that influences the rendered image of the card.
+func (cc *CardConjurer) cardInputs(card common.CardInfo) (ManifestInputs, error) {
+	pipeline := make([]string, 0, len(cc.pipeline))
+	for _, st := range cc.pipeline {
+		pipeline = append(pipeline, st.name)
+	}
+
+	artworkHash, err := hashFile(cc.config.artworkPath(card))
+	if err != nil {
+		return ManifestInputs{}, err
+	}
+
+	var option string
+	if pin, ok := cc.lock.pin(card.GetPrintingID()); ok {
+		option = pin.OptionText
+	}
+
+	return ManifestInputs{
+		Printing:    card.GetFullName(),
+		Option:      option,
+		Frame:       cc.config.frameFor(card),
+		ArtworkHash: artworkHash,
+		Pipeline:    pipeline,
+		Capture:     cc.config.captureMode(),
+	}, nil
+}
+
+// upToDate checks the manifest, cards that are forced are never up to date.
+func (cc *CardConjurer) upToDate(card common.CardInfo) (ManifestEntry, bool) {
+	if cc.manifest == nil || cc.config.Force.forced(card) {
+		return ManifestEntry{}, false
+	}
+
+	inputs, err := cc.cardInputs(card)
+	if err != nil {
+		cc.logger.Warnw("Could not check manifest, rendering card", "card", card.GetFullName(), "error", err)
+		return ManifestEntry{}, false
+	}
+	return cc.manifest.upToDate(card.GetPrintingID(), inputs, cc.config.outputPath(card))
+}
+
+// skipUpToDate hands a card that doesn't need to be rendered again straight to the output,
+// so it still ends up in the order.
+func (cc *CardConjurer) skipUpToDate(card common.CardInfo, entry ManifestEntry) {
+	cc.logger.Infof("Card '%s' is up to date, skipping render.", card.GetFullName())
+	result := newCardReport(card, -1)
+	result.Status = StatusSkipped
+	result.Reason = "up to date"
+	result.OutputPath = entry.OutputPath
+	cc.addResult(result)
+	cc.outputChan <- card
+}
+
+func (cc *CardConjurer) startWorker(w *worker, ctx, browserCtx context.Context, wg *sync.WaitGroup) {
+	cc.logger.Infof("Starting worker %d", w.workerID)
 
 	defer func() {
-		cc.logger.Infof("Worker %d: finished", id)
+		cc.logger.Infof("Worker %d: finished", w.workerID)
 		wg.Done()
 	}()
 
-	w := newWorker(id, cc.logger, cc.config)
-	w.startWorker(ctx, cc.cardsChan, cc.outputChan)
+	w.startWorker(ctx, browserCtx, cc.cardsChan, cc.outputChan)
+}
+
+// newWorker creates a worker that shares the offline data and network cache of the run.
+func (cc *CardConjurer) newWorker(id int, logger *zap.SugaredLogger, pipeline []step) *worker {
+	w := newWorker(id, logger, cc.config, pipeline)
+	w.scryfall = cc.scryfall
+	w.cache = cc.cache
+	return w
+}
+
+// saveCache writes the responses recorded during the run.
+func (cc *CardConjurer) saveCache() {
+	if cc.cache == nil {
+		return
+	}
+	if err := cc.cache.Save(); err != nil {
+		cc.logger.Errorw("Could not write network cache", "dir", cc.config.NetworkCacheDir, "error", err)
+	}
+}
+
+func (cc *CardConjurer) addResult(result CardReport) {
+	if res, ok := cc.resolutions[result.card]; ok && res.Text == result.Resolved {
+		result.Fallback = string(res.Fallback)
+	}
+
+	cc.resultsMu.Lock()
+	cc.results[result.card] = result
+	cc.resultsMu.Unlock()
+
+	if result.Status != StatusSucceeded {
+		return
+	}
+	if result.option.Text != "" {
+		cc.pin(result.card, result.option)
+	}
+	if cc.manifest == nil {
+		return
+	}
+
+	inputs, err := cc.cardInputs(result.card)
+	if err != nil {
+		cc.logger.Warnw("Could not update manifest", "card", result.Name, "error", err)
+		return
+	}
+	cc.manifest.set(result.card.GetPrintingID(), ManifestEntry{
+		ManifestInputs: inputs,
+		OutputPath:     result.OutputPath,
+		Rendered:       time.Now(),
+	})
+	// Saved after every card, so an aborted run keeps what was rendered so far
+	if err := cc.manifest.Save(); err != nil {
+		cc.logger.Errorw("Could not write manifest", "error", err)
+	}
+}
+
+// Report returns the outcome of every card of the last Run in decklist order.
+// Cards that were never processed are reported as skipped.
+func (cc *CardConjurer) Report() *Report {
+	cc.resultsMu.Lock()
+	defer cc.resultsMu.Unlock()
+
+	report := &Report{
+		Project:  cc.config.ProjectName,
+		Started:  cc.started,
+		Finished: cc.finished,
+	}
+	for _, card := range cc.cards {
+		result, ok := cc.results[card]
+		if !ok {
+			result = newCardReport(card, -1)
+			result.Status = StatusSkipped
+			result.Reason = "not processed"
+		}
+		report.add(result)
+	}
+	return report
 }
@@ -0,0 +1,104 @@
+// Package cctest serves a minimal Card Conjurer look-alike for end-to-end tests. The page has
+// every element of the default selector profile and answers card searches from a fixed list
+// of printings, so the worker flow runs in a headless Chrome without network access.
+package cctest
+
+import (
+	"bytes"
+	"embed"
+	"encoding/json"
+	"image"
+	"image/color"
+	"image/png"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+)
+
+//go:embed site
+var site embed.FS
+
+// Printing is a card the fake search knows about.
+type Printing struct {
+	Name            string `json:"name"`
+	Set             string `json:"set"`
+	CollectorNumber string `json:"collector_number"`
+	Released        string `json:"released_at,omitempty"`
+}
+
+// Server is a running fake Card Conjurer, its URL is the base url for the tests.
+type Server struct {
+	*httptest.Server
+
+	mu        sync.Mutex
+	printings []Printing
+	searches  []string
+}
+
+// NewServer starts a fake Card Conjurer whose search returns the given printings.
+// The server must be closed with Close.
+func NewServer(printings ...Printing) *Server {
+	s := &Server{printings: printings}
+
+	root, err := fs.Sub(site, "site")
+	if err != nil {
+		panic(err)
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/", http.FileServerFS(root))
+	mux.HandleFunc("/cards/search", s.handleSearch)
+	mux.HandleFunc("/img/frames/", handleThumbnail)
+	s.Server = httptest.NewServer(mux)
+	return s
+}
+
+// AddPrintings makes more printings available to the search.
+func (s *Server) AddPrintings(printings ...Printing) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.printings = append(s.printings, printings...)
+}
+
+// Searches returns the card names searched so far in order.
+func (s *Server) Searches() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.searches...)
+}
+
+// handleSearch answers like Scryfall's card search: all printings whose name matches the query.
+func (s *Server) handleSearch(w http.ResponseWriter, r *http.Request) {
+	query := strings.TrimSpace(r.URL.Query().Get("q"))
+
+	s.mu.Lock()
+	s.searches = append(s.searches, query)
+	data := []Printing{}
+	for _, p := range s.printings {
+		if strings.EqualFold(p.Name, query) {
+			data = append(data, p)
+		}
+	}
+	s.mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"object": "list", "data": data})
+}
+
+// handleThumbnail serves a small gray PNG for every frame image.
+func handleThumbnail(w http.ResponseWriter, r *http.Request) {
+	img := image.NewGray(image.Rect(0, 0, 16, 16))
+	for i := range img.Pix {
+		img.Pix[i] = color.Gray{Y: 128}.Y
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "image/png")
+	w.Write(buf.Bytes())
+}
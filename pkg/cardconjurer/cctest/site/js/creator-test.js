@@ -0,0 +1,138 @@
+// Minimal stand-in for Card Conjurer's creator script. The state of the card is drawn onto
+// cardCanvas, so every change the automation makes is visible in the rendered image.
+
+var cardCanvas = document.createElement('canvas');
+cardCanvas.width = 750;
+cardCanvas.height = 1050;
+
+var scryfallCard = [];
+
+var card = {
+	name: '',
+	printing: '',
+	frame: 'Seventh',
+	margin: false,
+	art: null,
+	setSymbol: true,
+};
+
+function toggleCreatorTabs(event, target) {
+	document.querySelectorAll('.creator-menu-section').forEach(section => section.classList.add('hidden'));
+	document.querySelector('#creator-menu-' + target).classList.remove('hidden');
+}
+
+// Values set by the automation don't fire change events, so the inputs the page reacts to are polled.
+var lastFrameGroup = '';
+var lastIndex = '';
+setInterval(() => {
+	const group = document.querySelector('#selectFrameGroup').value;
+	if (group !== lastFrameGroup) {
+		lastFrameGroup = group;
+		loadFrameGroup(group);
+	}
+	const index = document.querySelector('#import-index').value;
+	if (index !== lastIndex) {
+		lastIndex = index;
+		changeCardIndex();
+	}
+}, 50);
+
+function loadFrameGroup(group) {
+	const picker = document.querySelector('#frame-picker');
+	picker.innerHTML = '';
+	const thumbnails = {
+		Standard: ['/img/frames/standard/thumb.png'],
+		Margin: ['/img/frames/margins/blackBorderExtensionThumb.png'],
+	}[group] || [];
+	for (const src of thumbnails) {
+		const img = document.createElement('img');
+		img.src = src;
+		picker.appendChild(img);
+	}
+}
+
+function addFrameToFull() {
+	if (document.querySelector('#selectFrameGroup').value === 'Margin') {
+		card.margin = true;
+	}
+	drawCard();
+}
+
+async function searchCard(name) {
+	const response = await fetch('/cards/search?order=released&unique=prints&q=' + encodeURIComponent(name));
+	const result = await response.json();
+	scryfallCard = result.data || [];
+
+	const select = document.querySelector('#import-index');
+	select.innerHTML = '';
+	scryfallCard.forEach((c, i) => {
+		const option = document.createElement('option');
+		option.value = i;
+		option.textContent = `${c.name} (${c.set.toUpperCase()} #${c.collector_number})`;
+		select.appendChild(option);
+	});
+	lastIndex = '';
+}
+
+function changeCardIndex() {
+	const c = scryfallCard[document.querySelector('#import-index').value];
+	if (!c) {
+		return;
+	}
+	card.name = c.name;
+	card.printing = `${c.set.toUpperCase()} #${c.collector_number}`;
+	card.frame = document.querySelector('#autoFrame').value;
+	drawCard();
+}
+
+function uploadArt(file) {
+	if (!file) {
+		return;
+	}
+	createImageBitmap(file).then(bitmap => {
+		card.art = bitmap;
+		drawCard();
+	});
+}
+
+function removeSetSymbol() {
+	card.setSymbol = false;
+	drawCard();
+}
+
+function drawCard() {
+	const ctx = cardCanvas.getContext('2d');
+	ctx.fillStyle = card.margin ? '#000000' : '#ffffff';
+	ctx.fillRect(0, 0, cardCanvas.width, cardCanvas.height);
+	ctx.fillStyle = '#d8c8a8';
+	ctx.fillRect(37, 37, cardCanvas.width - 74, cardCanvas.height - 74);
+	if (card.art) {
+		ctx.drawImage(card.art, 75, 125, 600, 450);
+	}
+	ctx.fillStyle = '#000000';
+	ctx.font = '36px sans-serif';
+	ctx.fillText(card.name, 75, 100);
+	ctx.font = '24px sans-serif';
+	ctx.fillText(card.printing + ' ' + card.frame, 75, 1000);
+	if (card.setSymbol) {
+		ctx.beginPath();
+		ctx.arc(650, 620, 20, 0, 2 * Math.PI);
+		ctx.fill();
+	}
+
+	const preview = document.querySelector('#previewCanvas');
+	const previewCtx = preview.getContext('2d');
+	previewCtx.clearRect(0, 0, preview.width, preview.height);
+	previewCtx.drawImage(cardCanvas, 0, 0, preview.width, preview.height);
+}
+
+function downloadCard() {
+	const link = document.createElement('a');
+	link.download = (card.name || 'card') + '.png';
+	link.href = cardCanvas.toDataURL('image/png');
+	document.body.appendChild(link);
+	link.click();
+	link.remove();
+}
+
+drawCard();
@@ -0,0 +1,117 @@
+package cardconjurer
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// marginThumbnailTimeout is how long the check waits for the margin frames to load
+const marginThumbnailTimeout = 10 * time.Second
+
+// SelectorCheck is the result of looking up one entry of the selector profile on the page.
+type SelectorCheck struct {
+	Name     string `json:"name"`
+	Selector string `json:"selector"`
+	Found    bool   `json:"found"`
+}
+
+type SelectorChecks []SelectorCheck
+
+// Missing returns the checks whose selector did not resolve.
+func (c SelectorChecks) Missing() SelectorChecks {
+	var missing SelectorChecks
+	for _, check := range c {
+		if !check.Found {
+			missing = append(missing, check)
+		}
+	}
+	return missing
+}
+
+func (c SelectorChecks) WriteTable(out io.Writer) error {
+	tw := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "STATUS\tNAME\tSELECTOR")
+	for _, check := range c {
+		status := "ok"
+		if !check.Found {
+			status = "missing"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", status, check.Name, check.Selector)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	_, err := fmt.Fprintf(out, "%d of %d selectors resolved\n", len(c)-len(c.Missing()), len(c))
+	return err
+}
+
+// checkSelectors looks up every selector on the loaded page. Elements of inactive tabs are
+// part of the DOM as well, only the margin thumbnail needs the margin frame group selected.
+func (w *worker) checkSelectors(browserCtx context.Context) (SelectorChecks, error) {
+	sel := w.config.selectors()
+
+	var checks SelectorChecks
+	exists := func(name, selector string) error {
+		var found bool
+		if err := w.driver.Evaluate(browserCtx, fmt.Sprintf(`document.querySelector(%s) !== null`, jsString(selector)), &found); err != nil {
+			// An invalid selector makes querySelector throw
+			if !strings.Contains(err.Error(), "SyntaxError") {
+				return err
+			}
+			w.logger.Warnw("Invalid selector", "name", name, "selector", selector, "error", err)
+		}
+		checks = append(checks, SelectorCheck{Name: name, Selector: selector, Found: found})
+		return nil
+	}
+
+	domSelectors := []struct{ name, selector string }{
+		{"tabs.import", sel.tab(sel.Tabs.Import)},
+		{"tabs.frame", sel.tab(sel.Tabs.Frame)},
+		{"tabs.art", sel.tab(sel.Tabs.Art)},
+		{"tabs.set_symbol", sel.tab(sel.Tabs.SetSymbol)},
+		{"import_name", sel.ImportName},
+		{"import_index", sel.ImportIndex},
+		{"import_all_prints", sel.ImportAllPrints},
+		{"auto_frame", sel.AutoFrame},
+		{"frame_group", sel.FrameGroup},
+		{"margin_frame_group", fmt.Sprintf(`%s option[value="%s"]`, sel.FrameGroup, sel.MarginFrameGroup)},
+		{"add_to_full", sel.AddToFull},
+		{"art_upload", sel.ArtUpload},
+		{"remove_set_symbol", sel.RemoveSetSymbol},
+		{"download", sel.Download},
+		{"preview_canvas", sel.PreviewCanvas},
+	}
+	for _, s := range domSelectors {
+		if err := exists(s.name, s.selector); err != nil {
+			return nil, err
+		}
+	}
+
+	var canvas bool
+	if err := w.driver.Evaluate(browserCtx, fmt.Sprintf(`typeof window[%s] !== 'undefined'`, jsString(sel.CardCanvasVariable)), &canvas); err != nil {
+		return nil, err
+	}
+	checks = append(checks, SelectorCheck{Name: "card_canvas_variable", Selector: sel.CardCanvasVariable, Found: canvas})
+
+	// The thumbnails of a frame group are only loaded once the group is selected
+	thumbnail := SelectorCheck{Name: "margin_thumbnail", Selector: sel.MarginThumbnail}
+	ctx, cancel := context.WithTimeout(browserCtx, marginThumbnailTimeout)
+	defer cancel()
+	if err := w.openTab(ctx, sel.Tabs.Frame, sel.FrameGroup); err != nil {
+		w.logger.Warnw("Could not open frame tab to check the margin thumbnail", "error", err)
+	} else {
+		err := w.driver.SetValue(ctx, sel.FrameGroup, sel.MarginFrameGroup)
+		if err == nil {
+			err = w.driver.WaitReady(ctx, sel.MarginThumbnail)
+		}
+		thumbnail.Found = err == nil
+	}
+	checks = append(checks, thumbnail)
+
+	return checks, nil
+}
@@ -1,9 +1,174 @@
 package cardconjurer
 
+import (
+	"cardconjurer-automation/pkg/common"
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+	"time"
+)
+
+// DefaultFrame is the Card Conjurer frame used if Config.Frame is empty.
+const DefaultFrame = "Seventh"
+
+// DefaultShutdownTimeout is used if Config.ShutdownTimeout is not set.
+const DefaultShutdownTimeout = 30 * time.Second
+
+// Frames lists common values of Card Conjurer's 'autoFrame' dropdown.
+// The options of the loaded page are authoritative, labels like "7th Edition" work as well.
+var Frames = []string{"Seventh", "Eighth", "M15", "M15Eighth", "Borderless", "FullArtNew", "Etched"}
+
 type Config struct {
 	Workers            int
 	BaseUrl            string
 	InputArtworkFolder string
 	OutputCardsFolder  string
 	ProjectName        string
+	Naming             common.NamingScheme
+	// SiteDir is a local Card Conjurer checkout, it is served on a loopback port while
+	// running and BaseUrl is set to its creator page
+	SiteDir string
+	// SitePage is the path of the creator within SiteDir, empty uses DefaultSitePage
+	SitePage string
+	// Frame is the default Card Conjurer frame, a "frame" decklist column overrides it per card
+	Frame string
+	// CaptureMode is CaptureCanvas (default) or CaptureDownload
+	CaptureMode string
+	// Fallback decides what is rendered if Card Conjurer doesn't offer the printing of a card
+	Fallback FallbackPolicy
+	// PreferredSets are tried in order by FallbackPreferred
+	PreferredSets []string
+	// MatchThreshold is the minimum score (0-100) a search result needs to match a card,
+	// 0 uses DefaultMatchThreshold
+	MatchThreshold float64
+	// Retry controls how failed cards are retried, an unset policy uses DefaultRetryPolicy
+	Retry RetryPolicy
+	// Pipeline lists the steps run for every card in order, see DefaultPipeline
+	Pipeline []string
+	// ManifestPath enables incremental rendering, cards whose inputs did not change
+	// since the last run are not rendered again
+	ManifestPath string
+	// LockPath enables pinning, the printing chosen for every card is recorded there
+	// and reused by later runs instead of searching again
+	LockPath string
+	// Force renders the selected cards even if they are up to date
+	Force   *ForceSet
+	Browser BrowserConfig
+	// Selectors is the profile of the Card Conjurer UI, nil uses the embedded default
+	Selectors *Selectors
+	// ScryfallBulkPath enables offline mode: the Scryfall requests of the page are answered
+	// from this bulk data file instead of the network
+	ScryfallBulkPath string
+	// ScryfallURLPattern selects the intercepted requests, empty uses DefaultScryfallURLPattern
+	ScryfallURLPattern string
+	// NetworkCacheDir is where NetworkCacheMode records responses and replays them from
+	NetworkCacheDir  string
+	NetworkCacheMode NetworkCacheMode
+	// ShutdownTimeout is how long cards in progress may take to finish after cancellation
+	ShutdownTimeout time.Duration
+
+	// siteURL is the root of the site served from SiteDir while running
+	siteURL string
+}
+
+// frameFor returns the frame of the card, a "frame" column in the decklist overrides the configured one.
+func (c *Config) frameFor(card common.CardInfo) string {
+	if frame, ok := card.GetExtra("frame"); ok && strings.TrimSpace(frame) != "" {
+		return strings.TrimSpace(frame)
+	}
+	if c.Frame != "" {
+		return c.Frame
+	}
+	return DefaultFrame
+}
+
+func (c *Config) captureMode() string {
+	if c.CaptureMode != "" {
+		return c.CaptureMode
+	}
+	return CaptureCanvas
+}
+
+// outputPath returns where the rendered card is stored in the output folder.
+func (c *Config) outputPath(card common.CardInfo) string {
+	return path.Join(c.OutputCardsFolder, common.CardFileName(c.ProjectName, card, c.Naming))
+}
+
+// selectors returns the configured selector profile or the embedded default.
+func (c *Config) selectors() *Selectors {
+	if c.Selectors != nil {
+		return c.Selectors
+	}
+	return embeddedSelectors
+}
+
+func (c *Config) matchThreshold() float64 {
+	if c.MatchThreshold > 0 {
+		return c.MatchThreshold
+	}
+	return DefaultMatchThreshold
+}
+
+// artworkPath returns where the artwork of the card is expected in the artwork folder.
+func (c *Config) artworkPath(card common.CardInfo) string {
+	return fmt.Sprintf("%s/%s.png", c.InputArtworkFolder, card.GetName())
+}
+
+func (c *Config) scryfallURLPattern() string {
+	if c.ScryfallURLPattern != "" {
+		return c.ScryfallURLPattern
+	}
+	return DefaultScryfallURLPattern
+}
+
+// Validate checks the configuration before any browser is started.
+func (c *Config) Validate() error {
+	if c.Workers < 1 {
+		return fmt.Errorf("at least one worker is required, got %d", c.Workers)
+	}
+
+	if c.SiteDir != "" && c.BaseUrl != "" {
+		return errors.New("set either a base url or a site directory, not both")
+	}
+
+	switch c.CaptureMode {
+	case "", CaptureCanvas, CaptureDownload:
+	default:
+		return fmt.Errorf("unknown capture mode %q, expected %q or %q", c.CaptureMode, CaptureCanvas, CaptureDownload)
+	}
+
+	if _, err := ParseFallbackPolicy(string(c.Fallback)); err != nil {
+		return err
+	}
+	if c.Fallback == FallbackPreferred && len(c.PreferredSets) == 0 {
+		return fmt.Errorf("fallback policy %q needs at least one preferred set", FallbackPreferred)
+	}
+
+	if _, err := ParseNetworkCacheMode(string(c.NetworkCacheMode)); err != nil {
+		return err
+	}
+	if c.NetworkCacheMode != "" && c.NetworkCacheMode != NetworkCachePassthrough && c.NetworkCacheDir == "" {
+		return fmt.Errorf("network cache mode %q needs a cache directory", c.NetworkCacheMode)
+	}
+
+	if c.MatchThreshold < 0 || c.MatchThreshold > 100 {
+		return fmt.Errorf("match threshold must be between 0 and 100, got %v", c.MatchThreshold)
+	}
+
+	if c.Selectors != nil {
+		if err := c.Selectors.Validate(); err != nil {
+			return fmt.Errorf("invalid selector profile: %v", err)
+		}
+	}
+
+	if err := c.Browser.Validate(); err != nil {
+		return fmt.Errorf("invalid browser config: %v", err)
+	}
+	// Downloads end up on the filesystem of the remote browser
+	if len(c.Browser.RemoteURLs) > 0 && c.CaptureMode == CaptureDownload {
+		return fmt.Errorf("capture mode %q is not supported with a remote browser, use %q", CaptureDownload, CaptureCanvas)
+	}
+
+	return nil
 }
@@ -3,84 +3,59 @@ package cardconjurer
 import (
 	"cardconjurer-automation/pkg/common"
 	"context"
+	"errors"
 	"fmt"
-	"github.com/chromedp/chromedp"
 	"io"
 	"os"
-	"path"
-	"strings"
+	"path/filepath"
 	"time"
 )
 
-func (w *worker) saveCard(card common.CardInfo, browserCtx context.Context) error {
-	w.logger.Info("Saving card")
+// downloadTimeout is a safety net, failed downloads are reported by the browser right away
+const downloadTimeout = 60 * time.Second
 
-	homeDir, err := os.UserHomeDir()
-	if err != nil {
-		return fmt.Errorf("could not determine home directory: %v", err)
-	}
+// outputPath returns where the rendered card is stored in the output folder.
+func (w *worker) outputPath(card common.CardInfo) string {
+	return w.config.outputPath(card)
+}
 
-	// Expected filename (always with straight apostrophe)
-	filename := fmt.Sprintf("%s.png", card.GetName())
-	downloadPath := path.Join(homeDir, "Downloads", filename)
-	altFilename := strings.ReplaceAll(filename, "'", "’")
-	altDownloadPath := path.Join(homeDir, "Downloads", altFilename)
-	targetPath := path.Join(w.config.OutputCardsFolder, fmt.Sprintf("%s_%s.png", w.config.ProjectName, card.GetSanitizedName()))
+func (w *worker) saveCard(card common.CardInfo, browserCtx context.Context) error {
+	w.logger.Info("Saving card")
 
-	// Before download: Delete existing file in download folder if present (both variants)
-	if _, err := os.Stat(downloadPath); err == nil {
-		w.logger.Infof("Deleting existing file in download folder: %s", downloadPath)
-		if err := os.Remove(downloadPath); err != nil {
-			return fmt.Errorf("error deleting old download file: %v", err)
-		}
-	}
-	if altDownloadPath != downloadPath {
-		if _, err := os.Stat(altDownloadPath); err == nil {
-			w.logger.Infof("Deleting existing file in download folder: %s", altDownloadPath)
-			if err := os.Remove(altDownloadPath); err != nil {
-				return fmt.Errorf("error deleting old alternative download file: %v", err)
-			}
+	targetPath := w.outputPath(card)
+	if w.config.CaptureMode != CaptureDownload {
+		err := w.captureCanvas(targetPath, browserCtx)
+		if err == nil {
+			return nil
 		}
+		w.logger.Warnw("Could not capture card from canvas, falling back to download", "error", err)
 	}
 
-	// Click download button
-	if err := chromedp.Run(browserCtx,
-		chromedp.Click(`h3.download[onclick*="downloadCard"]`),
-	); err != nil {
-		return err
+	return w.downloadCard(card, targetPath, browserCtx)
+}
+
+// downloadCard clicks the download button and moves the downloaded file to the target path.
+func (w *worker) downloadCard(card common.CardInfo, targetPath string, browserCtx context.Context) error {
+	if w.downloadDir == "" {
+		return errors.New("no download directory configured for this browser session")
 	}
 
-	// Wait for file in download folder (check both variants)
-	timeout := time.After(20 * time.Second)
-	ticker := time.NewTicker(500 * time.Millisecond)
-	defer ticker.Stop()
+	ctx, cancel := context.WithTimeout(browserCtx, downloadTimeout)
+	defer cancel()
 
-	var foundPath string
-	for foundPath == "" {
-		select {
-		case <-timeout:
-			return fmt.Errorf("timeout waiting for download: %s or %s", downloadPath, altDownloadPath)
-		case <-ticker.C:
-			if _, err := os.Stat(downloadPath); err == nil {
-				foundPath = downloadPath
-				w.logger.Infof("Card saved: %s", downloadPath)
-			}
-			if altDownloadPath != downloadPath {
-				if _, err := os.Stat(altDownloadPath); err == nil {
-					foundPath = altDownloadPath
-					w.logger.Infof("Card saved (typographic apostrophe): %s", altDownloadPath)
-				}
-			}
-		}
+	foundPath, err := w.driver.Download(ctx, w.config.selectors().Download, w.downloadDir)
+	if err != nil {
+		return fmt.Errorf("error downloading %s: %v", card.GetName(), err)
 	}
 
-	if foundPath == "" {
-		return fmt.Errorf("could not find downloaded file: %s or %s", downloadPath, altDownloadPath)
-	}
+	w.logger.Infof("Card downloaded: %s", filepath.Base(foundPath))
+	return w.moveFile(foundPath, targetPath)
+}
 
-	// Move file to target directory (always with straight apostrophe in target name)
+// moveFile moves the downloaded file to the target path.
+func (w *worker) moveFile(foundPath, targetPath string) error {
 	w.logger.Infof("Moving file to: %s", targetPath)
-	err = os.Rename(foundPath, targetPath)
+	err := os.Rename(foundPath, targetPath)
 	if err != nil {
 		// Fallback: Copy and delete if Rename fails (e.g. across filesystems)
 		input, errOpen := os.Open(foundPath)
@@ -0,0 +1,167 @@
+package cardconjurer
+
+import (
+	"bytes"
+	"cardconjurer-automation/pkg/common"
+	"cardconjurer-automation/pkg/decklist_parser"
+	"context"
+	"encoding/base64"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSaveCard(t *testing.T) {
+	card := &decklist_parser.Card{Count: 1, Name: "Sol Ring", Set: "C21", CollectorNumber: "263"}
+	canvasPNG := append(append([]byte{}, pngSignature...), "canvas"...)
+	downloadPNG := append(append([]byte{}, pngSignature...), "download"...)
+
+	tests := []struct {
+		name        string
+		mode        string
+		canvas      string
+		preview     string
+		download    []byte
+		downloadErr error
+		noDir       bool
+		want        []byte
+		wantErr     bool
+	}{
+		{
+			name:   "canvas",
+			mode:   CaptureCanvas,
+			canvas: "data:image/png;base64," + base64.StdEncoding.EncodeToString(canvasPNG),
+			want:   canvasPNG,
+		},
+		{
+			name:     "empty canvas falls back to download",
+			mode:     CaptureCanvas,
+			download: downloadPNG,
+			want:     downloadPNG,
+		},
+		{
+			name:     "preview canvas is not captured",
+			mode:     CaptureCanvas,
+			preview:  "data:image/png;base64," + base64.StdEncoding.EncodeToString(canvasPNG),
+			download: downloadPNG,
+			want:     downloadPNG,
+		},
+		{
+			name:     "canvas that is no PNG falls back to download",
+			mode:     CaptureCanvas,
+			canvas:   "data:image/png;base64," + base64.StdEncoding.EncodeToString([]byte("GIF89a")),
+			download: downloadPNG,
+			want:     downloadPNG,
+		},
+		{
+			name:     "download",
+			mode:     CaptureDownload,
+			canvas:   "data:image/png;base64," + base64.StdEncoding.EncodeToString(canvasPNG),
+			download: downloadPNG,
+			want:     downloadPNG,
+		},
+		{
+			name:        "failed download",
+			mode:        CaptureDownload,
+			downloadErr: errors.New("download of card.png failed: canceled"),
+			wantErr:     true,
+		},
+		{
+			name:     "no download directory",
+			mode:     CaptureDownload,
+			download: downloadPNG,
+			noDir:    true,
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := newFakeDriver()
+			if tt.preview != "" {
+				d.on(DefaultSelectors().PreviewCanvas, tt.preview)
+			}
+			d.on("toDataURL('image/png')", tt.canvas)
+			if tt.download != nil || tt.downloadErr != nil {
+				d.download = func(dir string) (string, error) {
+					if tt.downloadErr != nil {
+						return "", tt.downloadErr
+					}
+					path := filepath.Join(dir, "8f2c-guid")
+					return path, os.WriteFile(path, tt.download, 0644)
+				}
+			}
+
+			cfg := &Config{OutputCardsFolder: t.TempDir(), CaptureMode: tt.mode}
+			w := newTestWorker(t, cfg, d)
+			if !tt.noDir {
+				w.downloadDir = t.TempDir()
+			}
+
+			err := w.saveCard(card, context.Background())
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			data, err := os.ReadFile(w.outputPath(card))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(data, tt.want) {
+				t.Errorf("saved %q, expected %q", data, tt.want)
+			}
+		})
+	}
+}
+
+func TestMissingCanvasFallsBackToDownload(t *testing.T) {
+	card := &decklist_parser.Card{Count: 1, Name: "Sol Ring", Set: "C21", CollectorNumber: "263"}
+	downloadPNG := append(append([]byte{}, pngSignature...), "download"...)
+	pipeline, err := buildPipeline([]string{StepSave})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Every element is on the page, but the card canvas is not exposed
+	d := newFakeDriver().on("!== null", true)
+	d.download = func(dir string) (string, error) {
+		path := filepath.Join(dir, "8f2c-guid")
+		return path, os.WriteFile(path, downloadPNG, 0644)
+	}
+	cfg := &Config{OutputCardsFolder: t.TempDir(), CaptureMode: CaptureCanvas}
+	w := newTestWorker(t, cfg, d)
+	w.pipeline = pipeline
+	w.browserCtx = context.Background()
+	w.downloadDir = t.TempDir()
+
+	checks, err := w.checkSelectors(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if missing := checks.Missing(); len(missing) != 1 || missing[0].Name != "card_canvas_variable" {
+		t.Fatalf("expected only the card canvas to be missing, got %v", missing)
+	}
+	cc := &CardConjurer{config: cfg, logger: w.logger, pipeline: pipeline}
+	if err := cc.verifySite(&SiteCheck{Selectors: checks}, []common.CardInfo{card}); err != nil {
+		t.Fatalf("preflight failed: %v", err)
+	}
+
+	result := w.processCard(context.Background(), context.Background(), card)
+	if result.Status != StatusSucceeded {
+		t.Fatalf("%s after %d attempt(s): %s", result.Status, result.Attempts, result.Error)
+	}
+	data, err := os.ReadFile(result.OutputPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(data, downloadPNG) {
+		t.Errorf("saved %q, expected the downloaded card", data)
+	}
+}
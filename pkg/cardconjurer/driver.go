@@ -0,0 +1,180 @@
+package cardconjurer
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"github.com/chromedp/cdproto/browser"
+	"github.com/chromedp/chromedp"
+	"mime"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// driver is everything the pipeline steps do with the page. The context passed to every
+// method is the browser context of the worker's session.
+type driver interface {
+	Navigate(ctx context.Context, url string) error
+	Click(ctx context.Context, selector string) error
+	SetValue(ctx context.Context, selector, value string) error
+	// SendKeys focuses the element and types the keys, e.g. "\t"
+	SendKeys(ctx context.Context, selector, keys string) error
+	// Evaluate runs the script and unmarshals its result into res, which may be nil
+	Evaluate(ctx context.Context, script string, res any) error
+	// Upload sets the local file on the file input
+	Upload(ctx context.Context, selector, path string) error
+	WaitVisible(ctx context.Context, selector string) error
+	WaitReady(ctx context.Context, selector string) error
+	// Poll waits until the expression is truthy
+	Poll(ctx context.Context, expression string, interval time.Duration) error
+	// Download clicks the element and waits until the browser finished the download it
+	// started, the file is stored in dir. Returns the path of the downloaded file.
+	Download(ctx context.Context, selector, dir string) (string, error)
+}
+
+// chromedpDriver drives a Chrome through chromedp.
+type chromedpDriver struct {
+	// remote browsers can't read local files, uploads hand over the file content instead
+	remote bool
+}
+
+func (d *chromedpDriver) Navigate(ctx context.Context, url string) error {
+	return chromedp.Run(ctx, chromedp.Navigate(url))
+}
+
+func (d *chromedpDriver) Click(ctx context.Context, selector string) error {
+	return chromedp.Run(ctx, chromedp.Click(selector))
+}
+
+func (d *chromedpDriver) SetValue(ctx context.Context, selector, value string) error {
+	return chromedp.Run(ctx, chromedp.SetValue(selector, value))
+}
+
+func (d *chromedpDriver) SendKeys(ctx context.Context, selector, keys string) error {
+	return chromedp.Run(ctx, chromedp.Focus(selector), chromedp.SendKeys(selector, keys))
+}
+
+func (d *chromedpDriver) Evaluate(ctx context.Context, script string, res any) error {
+	return chromedp.Run(ctx, chromedp.Evaluate(script, res))
+}
+
+func (d *chromedpDriver) Upload(ctx context.Context, selector, path string) error {
+	if !d.remote {
+		return chromedp.Run(ctx, chromedp.SetUploadFiles(selector, []string{path}))
+	}
+	upload, err := uploadFileContent(selector, path)
+	if err != nil {
+		return err
+	}
+	return chromedp.Run(ctx, upload)
+}
+
+func (d *chromedpDriver) WaitVisible(ctx context.Context, selector string) error {
+	return chromedp.Run(ctx, chromedp.WaitVisible(selector))
+}
+
+func (d *chromedpDriver) WaitReady(ctx context.Context, selector string) error {
+	return chromedp.Run(ctx, chromedp.WaitReady(selector))
+}
+
+func (d *chromedpDriver) Poll(ctx context.Context, expression string, interval time.Duration) error {
+	return chromedp.Run(ctx, chromedp.Poll(expression, nil, chromedp.WithPollingInterval(interval)))
+}
+
+// Download detects completion from the download events of the browser, so a failed
+// download is reported right away. The browser must store downloads under their GUID.
+func (d *chromedpDriver) Download(ctx context.Context, selector, dir string) (string, error) {
+	// The listener is removed when the context is cancelled
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	began := make(chan *browser.EventDownloadWillBegin, 1)
+	finished := make(chan *browser.EventDownloadProgress, 8)
+	chromedp.ListenTarget(ctx, func(ev interface{}) {
+		// Called from the event loop, so never block here
+		switch ev := ev.(type) {
+		case *browser.EventDownloadWillBegin:
+			select {
+			case began <- ev:
+			default:
+			}
+		case *browser.EventDownloadProgress:
+			if ev.State == browser.DownloadProgressStateInProgress {
+				return
+			}
+			select {
+			case finished <- ev:
+			default:
+			}
+		}
+	})
+
+	if err := chromedp.Run(ctx, chromedp.Click(selector)); err != nil {
+		return "", err
+	}
+
+	var download *browser.EventDownloadWillBegin
+	select {
+	case <-ctx.Done():
+		return "", fmt.Errorf("download did not start: %v", ctx.Err())
+	case download = <-began:
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return "", fmt.Errorf("download of %s did not finish: %v", download.SuggestedFilename, ctx.Err())
+		case progress := <-finished:
+			if progress.GUID != download.GUID {
+				continue
+			}
+			if progress.State != browser.DownloadProgressStateCompleted {
+				return "", fmt.Errorf("download of %s failed: %s", download.SuggestedFilename, progress.State)
+			}
+			// With AllowAndName the file is stored under its GUID
+			return filepath.Join(dir, download.GUID), nil
+		}
+	}
+}
+
+// uploadFileContent sets a local file on a file input without the browser having access to
+// the path: the content is passed to the page, wrapped in a File and assigned to the input.
+// Like DOM.setFileInputFiles it fires the input and change events.
+func uploadFileContent(selector, path string) (chromedp.Action, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	args, err := json.Marshal([]string{selector, filepath.Base(path), mime.TypeByExtension(filepath.Ext(path)), base64.StdEncoding.EncodeToString(data)})
+	if err != nil {
+		return nil, err
+	}
+
+	script := fmt.Sprintf(`(([selector, name, type, content]) => {
+		const input = document.querySelector(selector);
+		if (!input) {
+			return false;
+		}
+		const bytes = Uint8Array.from(atob(content), c => c.charCodeAt(0));
+		const transfer = new DataTransfer();
+		transfer.items.add(new File([bytes], name, {type: type}));
+		input.files = transfer.files;
+		input.dispatchEvent(new Event('input', {bubbles: true}));
+		input.dispatchEvent(new Event('change', {bubbles: true}));
+		return true;
+	})(%s)`, args)
+
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		var ok bool
+		if err := chromedp.Evaluate(script, &ok).Do(ctx); err != nil {
+			return err
+		}
+		if !ok {
+			return fmt.Errorf("file input %s not found", selector)
+		}
+		return nil
+	}), nil
+}
@@ -0,0 +1,150 @@
+package cardconjurer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"go.uber.org/zap/zaptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeResult answers every evaluated script that contains match.
+type fakeResult struct {
+	match string
+	value any
+}
+
+// fakeDriver is a scripted page. Actions on selectors that are not part of the page fail,
+// scripts are answered by the first result whose match they contain and return nothing
+// otherwise. Polled expressions that are not truthy time out right away.
+type fakeDriver struct {
+	elements map[string]bool
+	results  []fakeResult
+	// download is called for a click on a download button with the download directory
+	download func(dir string) (string, error)
+
+	calls   []string
+	uploads map[string]string
+}
+
+// newFakeDriver returns a page with every element of the default selector profile.
+func newFakeDriver() *fakeDriver {
+	sel := DefaultSelectors()
+	d := &fakeDriver{
+		elements: make(map[string]bool),
+		uploads:  make(map[string]string),
+	}
+	for _, tab := range []string{sel.Tabs.Import, sel.Tabs.Frame, sel.Tabs.Art, sel.Tabs.SetSymbol} {
+		d.elements[sel.tab(tab)] = true
+	}
+	for _, selector := range []string{sel.ImportName, sel.ImportIndex, sel.ImportAllPrints, sel.AutoFrame, sel.FrameGroup,
+		sel.AddToFull, sel.MarginThumbnail, sel.ArtUpload, sel.RemoveSetSymbol, sel.Download, sel.PreviewCanvas} {
+		d.elements[selector] = true
+	}
+	return d
+}
+
+func (d *fakeDriver) on(match string, value any) *fakeDriver {
+	d.results = append(d.results, fakeResult{match: match, value: value})
+	return d
+}
+
+func (d *fakeDriver) element(action, selector string) error {
+	return d.record(action+" "+selector, selector)
+}
+
+func (d *fakeDriver) record(call, selector string) error {
+	d.calls = append(d.calls, call)
+	if !d.elements[selector] {
+		return fmt.Errorf("%s: element %s not found", call, selector)
+	}
+	return nil
+}
+
+func (d *fakeDriver) Navigate(ctx context.Context, url string) error {
+	d.calls = append(d.calls, "navigate "+url)
+	return nil
+}
+
+func (d *fakeDriver) Click(ctx context.Context, selector string) error {
+	return d.element("click", selector)
+}
+
+func (d *fakeDriver) SetValue(ctx context.Context, selector, value string) error {
+	return d.record("set "+selector+"="+value, selector)
+}
+
+func (d *fakeDriver) SendKeys(ctx context.Context, selector, keys string) error {
+	return d.element("keys", selector)
+}
+
+func (d *fakeDriver) Evaluate(ctx context.Context, script string, res any) error {
+	for _, result := range d.results {
+		if !strings.Contains(script, result.match) {
+			continue
+		}
+		if res == nil {
+			return nil
+		}
+		data, err := json.Marshal(result.value)
+		if err != nil {
+			return err
+		}
+		return json.Unmarshal(data, res)
+	}
+	return nil
+}
+
+func (d *fakeDriver) Upload(ctx context.Context, selector, path string) error {
+	if err := d.element("upload", selector); err != nil {
+		return err
+	}
+	d.uploads[selector] = path
+	return nil
+}
+
+func (d *fakeDriver) WaitVisible(ctx context.Context, selector string) error {
+	return d.element("visible", selector)
+}
+
+func (d *fakeDriver) WaitReady(ctx context.Context, selector string) error {
+	return d.element("ready", selector)
+}
+
+func (d *fakeDriver) Poll(ctx context.Context, expression string, interval time.Duration) error {
+	var truthy any
+	if err := d.Evaluate(ctx, expression, &truthy); err != nil {
+		return err
+	}
+	if truthy == nil || truthy == false {
+		return context.DeadlineExceeded
+	}
+	return nil
+}
+
+func (d *fakeDriver) Download(ctx context.Context, selector, dir string) (string, error) {
+	if err := d.element("click", selector); err != nil {
+		return "", err
+	}
+	if d.download == nil {
+		return "", fmt.Errorf("download did not start: %v", context.DeadlineExceeded)
+	}
+	return d.download(dir)
+}
+
+// called reports whether the driver got the call, e.g. "click #addToFull".
+func (d *fakeDriver) called(call string) bool {
+	return slices.Contains(d.calls, call)
+}
+
+func newTestWorker(t *testing.T, cfg *Config, d driver) *worker {
+	if cfg.ProjectName == "" {
+		cfg.ProjectName = "test"
+	}
+	w := newWorker(0, zaptest.NewLogger(t).Sugar(), cfg, nil)
+	w.driver = d
+	return w
+}
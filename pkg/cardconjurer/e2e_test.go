@@ -0,0 +1,246 @@
+package cardconjurer_test
+
+import (
+	"bytes"
+	"cardconjurer-automation/pkg/cardconjurer"
+	"cardconjurer-automation/pkg/cardconjurer/cctest"
+	"cardconjurer-automation/pkg/common"
+	"cardconjurer-automation/pkg/decklist_parser"
+	"context"
+	"encoding/json"
+	"errors"
+	"go.uber.org/zap/zaptest"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+var printings = []cctest.Printing{
+	{Name: "Sol Ring", Set: "ltc", CollectorNumber: "3", Released: "2023-06-23"},
+	{Name: "Sol Ring", Set: "c21", CollectorNumber: "263", Released: "2021-04-23"},
+	{Name: "Lightning Bolt", Set: "m11", CollectorNumber: "149", Released: "2010-07-16"},
+}
+
+// findChrome returns the Chrome used by the end-to-end tests, $CHROME_PATH or the first
+// browser found in $PATH. The test is skipped without one.
+func findChrome(t *testing.T) string {
+	t.Helper()
+	if testing.Short() {
+		t.Skip("end-to-end test skipped in short mode")
+	}
+	if path := os.Getenv("CHROME_PATH"); path != "" {
+		return path
+	}
+	for _, name := range []string{"google-chrome", "google-chrome-stable", "chromium", "chromium-browser", "chrome", "headless-shell"} {
+		if path, err := exec.LookPath(name); err == nil {
+			return path
+		}
+	}
+	t.Skip("no Chrome found, set CHROME_PATH to run the end-to-end tests")
+	return ""
+}
+
+func writeArtwork(t *testing.T, path string) {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, 64, 48))
+	for i := 0; i < len(img.Pix); i += 4 {
+		img.Pix[i], img.Pix[i+3] = 200, 255
+	}
+	img.Set(0, 0, color.Black)
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func newConfig(t *testing.T, chrome, baseUrl string) *cardconjurer.Config {
+	dir := t.TempDir()
+	cfg := &cardconjurer.Config{
+		Workers:            1,
+		BaseUrl:            baseUrl,
+		InputArtworkFolder: filepath.Join(dir, "artworks"),
+		OutputCardsFolder:  filepath.Join(dir, "cards"),
+		ProjectName:        "e2e",
+		Browser: cardconjurer.BrowserConfig{
+			Headless:   true,
+			ExecPath:   chrome,
+			ExtraFlags: []string{"no-sandbox"},
+		},
+		Retry: cardconjurer.RetryPolicy{MaxAttempts: 1},
+	}
+	for _, folder := range []string{cfg.InputArtworkFolder, cfg.OutputCardsFolder} {
+		if err := os.MkdirAll(folder, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return cfg
+}
+
+// run renders the cards and returns the report, the output channel is drained like mpc does.
+func run(t *testing.T, cfg *cardconjurer.Config, cards ...common.CardInfo) *cardconjurer.Report {
+	t.Helper()
+	cc, err := cardconjurer.New(cfg, zaptest.NewLogger(t).Sugar(), cards)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for range cc.GetOutputChan() {
+		}
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+	if err := cc.Run(ctx); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	<-done
+	return cc.Report()
+}
+
+func TestRunEndToEnd(t *testing.T) {
+	chrome := findChrome(t)
+	srv := cctest.NewServer(printings...)
+	defer srv.Close()
+
+	for _, mode := range []string{cardconjurer.CaptureCanvas, cardconjurer.CaptureDownload} {
+		t.Run(mode, func(t *testing.T) {
+			cfg := newConfig(t, chrome, srv.URL)
+			cfg.CaptureMode = mode
+			writeArtwork(t, filepath.Join(cfg.InputArtworkFolder, "Sol Ring.png"))
+
+			card := &decklist_parser.Card{Count: 1, Name: "Sol Ring", Set: "C21", CollectorNumber: "263"}
+			report := run(t, cfg, card)
+
+			if report.Succeeded != 1 || len(report.Cards) != 1 {
+				t.Fatalf("expected one rendered card, got %+v", report.Cards)
+			}
+			result := report.Cards[0]
+			if result.Resolved != "Sol Ring (C21 #263)" {
+				t.Errorf("rendered printing %q, expected Sol Ring (C21 #263)", result.Resolved)
+			}
+
+			data, err := os.ReadFile(result.OutputPath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			img, err := png.Decode(bytes.NewReader(data))
+			if err != nil {
+				t.Fatalf("output is not a PNG: %v", err)
+			}
+			if size := img.Bounds().Size(); size.X != 750 || size.Y != 1050 {
+				t.Errorf("expected the full size card canvas, got %v", size)
+			}
+		})
+	}
+}
+
+func TestRunUnresolvedCard(t *testing.T) {
+	chrome := findChrome(t)
+	srv := cctest.NewServer(printings...)
+	defer srv.Close()
+
+	cfg := newConfig(t, chrome, srv.URL)
+	report := run(t, cfg,
+		&decklist_parser.Card{Count: 1, Name: "Sol Ring", Set: "CMR", CollectorNumber: "472"},
+		&decklist_parser.Card{Count: 1, Name: "Lightning Bolt", Set: "M11", CollectorNumber: "149"},
+	)
+
+	if report.Failed != 1 || report.Succeeded != 1 {
+		t.Fatalf("expected one failed and one rendered card, got %+v", report.Cards)
+	}
+	if failed := report.Cards[0]; failed.FailedStep != "resolve" {
+		t.Errorf("expected Sol Ring to fail in the resolve step, got %+v", failed)
+	}
+}
+
+func TestRunOffline(t *testing.T) {
+	chrome := findChrome(t)
+	// The site knows no printing of Sol Ring, the bulk data does
+	srv := cctest.NewServer(printings[2:]...)
+	defer srv.Close()
+
+	cfg := newConfig(t, chrome, srv.URL)
+	bulk, err := json.Marshal(printings)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.ScryfallBulkPath = filepath.Join(t.TempDir(), "default-cards.json")
+	if err := os.WriteFile(cfg.ScryfallBulkPath, bulk, 0644); err != nil {
+		t.Fatal(err)
+	}
+	cfg.ScryfallURLPattern = srv.URL + "/cards/search*"
+
+	report := run(t, cfg, &decklist_parser.Card{Count: 1, Name: "Sol Ring", Set: "LTC", CollectorNumber: "3"})
+	if report.Succeeded != 1 {
+		t.Fatalf("expected the card to be rendered from the bulk data, got %+v", report.Cards)
+	}
+	if searches := srv.Searches(); len(searches) > 0 {
+		t.Errorf("searches reached the network: %q", searches)
+	}
+}
+
+func TestRunReplaysRecordedNetwork(t *testing.T) {
+	chrome := findChrome(t)
+	srv := cctest.NewServer(printings...)
+	cacheDir := t.TempDir()
+	card := &decklist_parser.Card{Count: 1, Name: "Lightning Bolt", Set: "M11", CollectorNumber: "149"}
+
+	cfg := newConfig(t, chrome, srv.URL)
+	cfg.NetworkCacheDir = cacheDir
+	cfg.NetworkCacheMode = cardconjurer.NetworkCacheRecord
+	if report := run(t, cfg, card); report.Succeeded != 1 {
+		srv.Close()
+		t.Fatalf("recording run failed: %+v", report.Cards)
+	}
+	searches := len(srv.Searches())
+	srv.Close()
+	if searches == 0 {
+		t.Fatal("the recording run did not search the card")
+	}
+
+	// The site is gone, everything has to come from the cache
+	cfg = newConfig(t, chrome, srv.URL)
+	cfg.NetworkCacheDir = cacheDir
+	cfg.NetworkCacheMode = cardconjurer.NetworkCacheReplay
+	if report := run(t, cfg, card); report.Succeeded != 1 {
+		t.Fatalf("replaying run failed: %+v", report.Cards)
+	}
+}
+
+func TestPreflightFailsOnWrongSite(t *testing.T) {
+	chrome := findChrome(t)
+	srv := cctest.NewServer()
+	defer srv.Close()
+
+	cfg := newConfig(t, chrome, srv.URL+"/cards/search")
+	card := &decklist_parser.Card{Count: 1, Name: "Sol Ring", Set: "C21", CollectorNumber: "263"}
+	cc, err := cardconjurer.New(cfg, zaptest.NewLogger(t).Sugar(), []common.CardInfo{card})
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		for range cc.GetOutputChan() {
+		}
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	var preflightErr *cardconjurer.PreflightError
+	if err := cc.Run(ctx); err == nil {
+		t.Fatal("expected the preflight check to fail")
+	} else if !errors.As(err, &preflightErr) {
+		t.Fatalf("expected a PreflightError, got %v", err)
+	}
+}
@@ -0,0 +1,193 @@
+package cardconjurer
+
+import (
+	"context"
+	"encoding/base64"
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/cdproto/fetch"
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/chromedp"
+	"regexp"
+	"strings"
+)
+
+// fetchResponse answers an intercepted request instead of the network. With Error set the
+// request fails with that network error.
+type fetchResponse struct {
+	Status     int
+	StatusText string
+	Headers    map[string]string
+	Body       []byte
+	Error      network.ErrorReason
+}
+
+// requestHandler handles the requests whose url matches pattern. It either serves them,
+// where nil lets the request through to the network, or records their responses. Chrome
+// pauses a request only in the stage of the first pattern it matches.
+type requestHandler struct {
+	name    string
+	pattern string
+	serve   func(req *network.Request) *fetchResponse
+	record  func(req *network.Request, resp *fetchResponse) error
+}
+
+// requestHandlers returns the handlers of the browser session, the first one that answers wins.
+// Offline Scryfall data takes precedence over the network cache.
+func (w *worker) requestHandlers() []requestHandler {
+	var handlers []requestHandler
+	if w.scryfall != nil {
+		handlers = append(handlers, requestHandler{
+			name:    "scryfall",
+			pattern: w.config.scryfallURLPattern(),
+			serve:   w.scryfall.serve,
+		})
+	}
+	if w.cache != nil {
+		// A served local site changes its port with every run and needs no cache
+		local := func(req *network.Request) bool {
+			return w.config.siteURL != "" && strings.HasPrefix(req.URL, w.config.siteURL+"/")
+		}
+		cache := requestHandler{name: "cache", pattern: "*"}
+		if w.cache.mode == NetworkCacheRecord {
+			cache.record = func(req *network.Request, resp *fetchResponse) error {
+				if local(req) {
+					return nil
+				}
+				return w.cache.record(req, resp)
+			}
+		} else {
+			cache.serve = func(req *network.Request) *fetchResponse {
+				if local(req) {
+					return nil
+				}
+				return w.cache.serve(req)
+			}
+		}
+		handlers = append(handlers, cache)
+	}
+	return handlers
+}
+
+// interceptRequests pauses the requests of the handlers through the CDP Fetch domain and
+// dispatches them to the handlers. It listens on the target of ctx and returns the action
+// that enables the interception, which must run before the page is loaded.
+func (w *worker) interceptRequests(ctx context.Context, handlers []requestHandler) chromedp.Action {
+	patterns := make([]*fetch.RequestPattern, 0, len(handlers))
+	matchers := make([]*regexp.Regexp, 0, len(handlers))
+	for _, h := range handlers {
+		stage := fetch.RequestStageRequest
+		if h.record != nil {
+			stage = fetch.RequestStageResponse
+		}
+		patterns = append(patterns, &fetch.RequestPattern{URLPattern: h.pattern, RequestStage: stage})
+		matchers = append(matchers, urlPatternRegexp(h.pattern))
+	}
+
+	chromedp.ListenTarget(ctx, func(ev interface{}) {
+		paused, ok := ev.(*fetch.EventRequestPaused)
+		if !ok {
+			return
+		}
+		// Called from the event loop, answering needs another round trip to the browser
+		go func() {
+			execCtx := cdp.WithExecutor(ctx, chromedp.FromContext(ctx).Target)
+			var err error
+			if paused.ResponseStatusCode != 0 || paused.ResponseErrorReason != "" {
+				err = w.recordResponse(execCtx, paused, handlers, matchers)
+			} else {
+				err = w.dispatchRequest(execCtx, paused, handlers, matchers)
+			}
+			if err != nil && ctx.Err() == nil {
+				w.logger.Warnw("Could not handle intercepted request", "url", paused.Request.URL, "error", err)
+			}
+		}()
+	})
+
+	return fetch.Enable().WithPatterns(patterns)
+}
+
+// dispatchRequest answers the request with the first handler that serves it, or lets it through.
+func (w *worker) dispatchRequest(ctx context.Context, paused *fetch.EventRequestPaused, handlers []requestHandler, matchers []*regexp.Regexp) error {
+	for i, h := range handlers {
+		if h.serve == nil || !matchers[i].MatchString(paused.Request.URL) {
+			continue
+		}
+		resp := h.serve(paused.Request)
+		if resp == nil {
+			continue
+		}
+		if resp.Error != "" {
+			w.logger.Warnw("Failing intercepted request", "handler", h.name, "url", paused.Request.URL, "reason", resp.Error)
+			return fetch.FailRequest(paused.RequestID, resp.Error).Do(ctx)
+		}
+		w.logger.Debugw("Answering intercepted request", "handler", h.name, "url", paused.Request.URL, "status", resp.Status)
+		return fulfillRequest(ctx, paused.RequestID, resp)
+	}
+	return fetch.ContinueRequest(paused.RequestID).Do(ctx)
+}
+
+// recordResponse hands the response of a request that went to the network to the handlers
+// recording it and passes it on to the page.
+func (w *worker) recordResponse(ctx context.Context, paused *fetch.EventRequestPaused, handlers []requestHandler, matchers []*regexp.Regexp) error {
+	if paused.ResponseErrorReason == "" && cacheable(int(paused.ResponseStatusCode)) {
+		var resp *fetchResponse
+		for i, h := range handlers {
+			if h.record == nil || !matchers[i].MatchString(paused.Request.URL) {
+				continue
+			}
+			if resp == nil {
+				body, err := fetch.GetResponseBody(paused.RequestID).Do(ctx)
+				if err != nil {
+					w.logger.Warnw("Could not read intercepted response", "url", paused.Request.URL, "error", err)
+					break
+				}
+				resp = &fetchResponse{
+					Status:     int(paused.ResponseStatusCode),
+					StatusText: paused.ResponseStatusText,
+					Headers:    make(map[string]string, len(paused.ResponseHeaders)),
+					Body:       body,
+				}
+				for _, header := range paused.ResponseHeaders {
+					resp.Headers[header.Name] = header.Value
+				}
+			}
+			if err := h.record(paused.Request, resp); err != nil {
+				w.logger.Warnw("Could not record response", "handler", h.name, "url", paused.Request.URL, "error", err)
+			}
+		}
+	}
+	return fetch.ContinueRequest(paused.RequestID).Do(ctx)
+}
+
+func fulfillRequest(ctx context.Context, id fetch.RequestID, resp *fetchResponse) error {
+	headers := make([]*fetch.HeaderEntry, 0, len(resp.Headers))
+	for name, value := range resp.Headers {
+		headers = append(headers, &fetch.HeaderEntry{Name: name, Value: value})
+	}
+	params := fetch.FulfillRequest(id, int64(resp.Status)).
+		WithResponseHeaders(headers).
+		WithBody(base64.StdEncoding.EncodeToString(resp.Body))
+	if resp.StatusText != "" {
+		params = params.WithResponsePhrase(resp.StatusText)
+	}
+	return params.Do(ctx)
+}
+
+// urlPatternRegexp compiles a Fetch domain url pattern, '*' matches any number of
+// characters and '?' a single one.
+func urlPatternRegexp(pattern string) *regexp.Regexp {
+	var sb strings.Builder
+	sb.WriteString("^")
+	for _, r := range pattern {
+		switch r {
+		case '*':
+			sb.WriteString(".*")
+		case '?':
+			sb.WriteString(".")
+		default:
+			sb.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	sb.WriteString("$")
+	return regexp.MustCompile(sb.String())
+}
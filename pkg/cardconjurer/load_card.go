@@ -3,61 +3,116 @@ package cardconjurer
 import (
 	"cardconjurer-automation/pkg/common"
 	"context"
+	"errors"
 	"fmt"
-	"github.com/chromedp/chromedp"
 	"strings"
 	"time"
 )
 
-func (w *worker) importCard(cardData common.CardInfo, browserCtx context.Context) error {
+// importCard imports the card into the creator and returns the printing that was selected.
+func (w *worker) importCard(cardData common.CardInfo, browserCtx context.Context) (selectOption, error) {
 	w.logger.Info("Starting import")
+	sel := w.config.selectors()
 
-	err := w.openTab(browserCtx, "import", "#import-name")
+	// Open import tab and wait for the name input and the frame dropdown to be visible
+	err := w.openTab(browserCtx, sel.Tabs.Import, sel.ImportName, sel.AutoFrame)
 	if err != nil {
 		w.logger.Errorw("Error opening import tab", "error", err)
-		return err
-	}
-
-	// Click import tab and wait for dropdown to be visible
-	if err := chromedp.Run(browserCtx,
-		chromedp.Click(`h3.selectable.readable-background[onclick*="toggleCreatorTabs"][onclick*="import"]`),
-		chromedp.WaitVisible(`#autoFrame`, chromedp.ByID),
-	); err != nil {
-		w.logger.Errorw("Error opening import tab", "error", err)
-		return err
+		return selectOption{}, err
 	}
 
-	w.logger.Info("Import tab opened, selecting option 'Seventh' in dropdown.")
-	// Select option 'Seventh' in dropdown with id 'autoFrame' and wait for checkbox to be ready
-	if err := chromedp.Run(browserCtx,
-		chromedp.SetValue(`#autoFrame`, "Seventh"),
-		chromedp.WaitReady(`#importAllPrints`, chromedp.ByID),
-	); err != nil {
-		w.logger.Errorw("Error selecting 'Seventh' in dropdown", "error", err)
-		return err
+	frame := w.config.frameFor(cardData)
+	w.logger.Infof("Import tab opened, selecting frame '%s' in dropdown.", frame)
+	if err := w.selectFrame(browserCtx, frame); err != nil {
+		w.logger.Errorw("Error selecting frame in dropdown", "frame", frame, "error", err)
+		return selectOption{}, err
 	}
 
 	// Further actions: check_import_all_prints and load_card
 	w.logger.Info("Checking 'Import All Prints' checkbox and loading card")
 	if err := w.checkImportAllPrints(browserCtx); err != nil {
 		w.logger.Errorw("Error checking 'Import All Prints' checkbox", "error", err)
-		return err
+		return selectOption{}, err
 	}
 	w.logger.Info("Loading card")
-	if err := w.loadCard(cardData, browserCtx); err != nil {
+	option, err := w.loadCard(cardData, browserCtx)
+	if err != nil {
 		w.logger.Errorw("Error loading card", "error", err)
+		return selectOption{}, err
+	}
+
+	return option, nil
+}
+
+// selectOption is an option of a dropdown on the page. Released is only set for
+// search results, if Card Conjurer exposes the Scryfall data of the results.
+type selectOption struct {
+	Value    string `json:"value"`
+	Text     string `json:"text"`
+	Released string `json:"released,omitempty"`
+}
+
+// selectFrame selects the frame in the 'autoFrame' dropdown. The frame is validated against
+// the options Card Conjurer offers, matching either the option value or its label.
+func (w *worker) selectFrame(browserCtx context.Context, frame string) error {
+	sel := w.config.selectors()
+	options, err := w.frameOptions(browserCtx)
+	if err != nil {
 		return err
 	}
 
-	return nil
+	value, err := matchFrame(frame, options)
+	if err != nil {
+		return err
+	}
+
+	// Select the option and wait for checkbox to be ready
+	if err := w.driver.SetValue(browserCtx, sel.AutoFrame, value); err != nil {
+		return err
+	}
+	return w.driver.WaitReady(browserCtx, sel.ImportAllPrints)
+}
+
+// frameOptions returns the options of the 'autoFrame' dropdown.
+func (w *worker) frameOptions(browserCtx context.Context) ([]selectOption, error) {
+	var options []selectOption
+	err := w.driver.Evaluate(browserCtx, fmt.Sprintf(`Array.from(document.querySelectorAll(%s)).map(o => ({value: o.value, text: o.textContent.trim()}))`,
+		jsString(w.config.selectors().AutoFrame+" option")), &options)
+	return options, err
+}
+
+// FrameError is returned if Card Conjurer does not offer a frame, it is not retried.
+type FrameError struct {
+	Frame     string
+	Available []selectOption
+}
+
+func (e *FrameError) Error() string {
+	return fmt.Sprintf("frame %q is not offered by Card Conjurer, available frames: %s", e.Frame, frameList(e.Available))
+}
+
+func frameList(options []selectOption) string {
+	available := make([]string, 0, len(options))
+	for _, option := range options {
+		available = append(available, fmt.Sprintf("%s (%s)", option.Value, option.Text))
+	}
+	return strings.Join(available, ", ")
+}
+
+func matchFrame(frame string, options []selectOption) (string, error) {
+	for _, option := range options {
+		if strings.EqualFold(option.Value, frame) || strings.EqualFold(option.Text, frame) {
+			return option.Value, nil
+		}
+	}
+	return "", &FrameError{Frame: frame, Available: options}
 }
 
 func (w *worker) checkImportAllPrints(browserCtx context.Context) error {
+	checkbox := jsString(w.config.selectors().ImportAllPrints)
 	var checked bool
 	// Check if checkbox is checked
-	err := chromedp.Run(browserCtx,
-		chromedp.EvaluateAsDevTools(`document.querySelector('#importAllPrints')?.checked`, &checked),
-	)
+	err := w.driver.Evaluate(browserCtx, fmt.Sprintf(`document.querySelector(%s)?.checked`, checkbox), &checked)
 	if err != nil {
 		w.logger.Errorw("Error checking checkbox state", "error", err)
 		return err
@@ -65,9 +120,7 @@ func (w *worker) checkImportAllPrints(browserCtx context.Context) error {
 	if !checked {
 		w.logger.Info("Checkbox 'Import All Prints' is not checked, clicking it.")
 		// Click parent element of checkbox and wait until checkbox is visible again
-		err = chromedp.Run(browserCtx,
-			chromedp.EvaluateAsDevTools(`document.querySelector('#importAllPrints').parentElement.click()`, nil),
-		)
+		err = w.driver.Evaluate(browserCtx, fmt.Sprintf(`document.querySelector(%s).parentElement.click()`, checkbox), nil)
 		if err != nil {
 			w.logger.Errorw("Error clicking checkbox", "error", err)
 			return err
@@ -76,78 +129,109 @@ func (w *worker) checkImportAllPrints(browserCtx context.Context) error {
 	return nil
 }
 
-func (w *worker) loadCard(cardData common.CardInfo, browserCtx context.Context) error {
+// searchCard enters the card name into the import field and returns the printings
+// Card Conjurer found. The import tab must be open.
+func (w *worker) searchCard(browserCtx context.Context, cardData common.CardInfo) ([]selectOption, error) {
+	sel := w.config.selectors()
+	indexOptions := jsString(sel.ImportIndex + " option")
+
 	// Before entering name: remove all options from dropdown
-	w.logger.Info("Removing all options from #import-index before new search")
-	if err := chromedp.Run(browserCtx,
-		chromedp.Evaluate(`document.querySelectorAll('#import-index option').forEach(o => o.remove())`, nil),
-	); err != nil {
+	w.logger.Infof("Removing all options from %s before new search", sel.ImportIndex)
+	if err := w.driver.Evaluate(browserCtx, fmt.Sprintf(`document.querySelectorAll(%s).forEach(o => o.remove())`, indexOptions), nil); err != nil {
 		w.logger.Warnw("Could not remove options in dropdown", "error", err)
 		// not a fatal error, continue
 	}
 
 	// Press tab: set focus, then send tab key as raw event, then wait for dropdown to be ready
-	if err := chromedp.Run(browserCtx,
-		chromedp.WaitVisible(`#import-name`, chromedp.ByID),
-		chromedp.WaitReady(`#import-name`, chromedp.ByID),
-		chromedp.SetValue(`#import-name`, cardData.GetName(), chromedp.ByID),
-		chromedp.Focus(`#import-name`, chromedp.ByID),
-		chromedp.ActionFunc(func(ctx context.Context) error {
-			return chromedp.SendKeys(`#import-name`, "\t", chromedp.ByID).Do(ctx)
-		}),
-		// Wait until at least one option in dropdown is loaded
-		chromedp.Poll(`document.querySelectorAll('#import-index option').length > 1`, nil, chromedp.WithPollingInterval(100*time.Millisecond)),
-		chromedp.WaitReady(`#import-index`, chromedp.ByID),
-	); err != nil {
+	err := w.driver.WaitVisible(browserCtx, sel.ImportName)
+	if err == nil {
+		err = w.driver.WaitReady(browserCtx, sel.ImportName)
+	}
+	if err == nil {
+		err = w.driver.SetValue(browserCtx, sel.ImportName, cardData.GetName())
+	}
+	if err == nil {
+		err = w.driver.SendKeys(browserCtx, sel.ImportName, "\t")
+	}
+	if err != nil {
 		w.logger.Errorw("Error preparing import fields", "error", err)
-		return err
+		return nil, err
 	}
 
-	// Build card version string
-	cardVersion := cardData.GetFullName()
+	// Wait until at least one option in dropdown is loaded, unknown names never get any
+	ctx, cancel := context.WithTimeout(browserCtx, searchTimeout)
+	defer cancel()
+	err = w.driver.Poll(ctx, fmt.Sprintf(`document.querySelectorAll(%s).length > 0`, indexOptions), 100*time.Millisecond)
+	if err == nil {
+		err = w.driver.WaitReady(ctx, sel.ImportIndex)
+	}
+	if err != nil {
+		if errors.Is(err, context.DeadlineExceeded) && browserCtx.Err() == nil {
+			return nil, nil
+		}
+		return nil, err
+	}
 
 	// Query all options in dropdown
-	var optionTexts []string
-	var optionValues []string
-	if err := chromedp.Run(browserCtx,
-		chromedp.Evaluate(`Array.from(document.querySelectorAll('#import-index option')).map(o => o.textContent.trim())`, &optionTexts),
-		chromedp.Evaluate(`Array.from(document.querySelectorAll('#import-index option')).map(o => o.value)`, &optionValues),
-	); err != nil {
+	var options []selectOption
+	if err := w.driver.Evaluate(browserCtx, fmt.Sprintf(`Array.from(document.querySelectorAll(%s)).map(o => ({
+			value: o.value,
+			text: o.textContent.trim(),
+			released: (window[%s] || [])[o.value]?.released_at || '',
+		}))`, indexOptions, jsString(sel.ScryfallCardsVariable)), &options); err != nil {
 		w.logger.Errorw("Error querying dropdown options", "error", err)
-		return err
+		return nil, err
 	}
 
-	// Find matching option (also check for exact match after trim)
-	var valueToSelect string
-	for i, text := range optionTexts {
-		if text == cardVersion {
-			valueToSelect = optionValues[i]
-			break
+	return options, nil
+}
+
+// loadCard searches the card and selects the printing found by the resolve phase or pinned
+// in the lock file. Cards that were not resolved beforehand, or whose pinned printing is
+// gone, are resolved against the search results.
+func (w *worker) loadCard(cardData common.CardInfo, browserCtx context.Context) (selectOption, error) {
+	sel := w.config.selectors()
+
+	options, err := w.searchCard(browserCtx, cardData)
+	if err != nil {
+		return selectOption{}, err
+	}
+
+	res, ok := w.resolutions[cardData]
+	if ok && res.Pinned {
+		if _, found := findOption(options, res.Text); !found {
+			w.logger.Warnw("Pinned printing no longer exists in Card Conjurer, resolving the card again", "pin", res.Text)
+			ok = false
 		}
 	}
-	if valueToSelect == "" {
-		// Try whitespace-insensitive search
-		for i, text := range optionTexts {
-			if strings.TrimSpace(text) == strings.TrimSpace(cardVersion) {
-				valueToSelect = optionValues[i]
-				break
-			}
+	if !ok {
+		res, err = resolveOption(cardData, options, w.config)
+		if err != nil {
+			return selectOption{}, err
 		}
 	}
 
-	if valueToSelect != "" {
-		// Select option and wait for dropdown to be ready again
-		if err := chromedp.Run(browserCtx,
-			chromedp.SetAttributeValue(fmt.Sprintf(`#import-index option[value="%s"]`, valueToSelect), "selected", "true"),
-			chromedp.SetValue(`#import-index`, valueToSelect),
-			chromedp.WaitReady(`#import-index`, chromedp.ByID),
-		); err != nil {
-			w.logger.Errorw("Error selecting card version in dropdown", "error", err)
-			return err
-		}
-	} else {
-		w.logger.Warnw("No matching card version found", "searched", cardVersion)
+	option, ok := findOption(options, res.Text)
+	if !ok {
+		return selectOption{}, fmt.Errorf("printing %q is no longer offered by Card Conjurer", res.Text)
+	}
+	if res.Fallback != "" {
+		w.logger.Warnw("Rendering fallback printing", "printing", option.Text, "fallback", res.Fallback)
 	}
 
-	return nil
+	// Select option and wait for dropdown to be ready again
+	err = w.driver.Evaluate(browserCtx, fmt.Sprintf(`Array.from(document.querySelectorAll(%s)).find(o => o.value === %s)?.setAttribute('selected', 'true')`,
+		jsString(sel.ImportIndex+" option"), jsString(option.Value)), nil)
+	if err == nil {
+		err = w.driver.SetValue(browserCtx, sel.ImportIndex, option.Value)
+	}
+	if err == nil {
+		err = w.driver.WaitReady(browserCtx, sel.ImportIndex)
+	}
+	if err != nil {
+		w.logger.Errorw("Error selecting card version in dropdown", "error", err)
+		return selectOption{}, err
+	}
+
+	return option, nil
 }
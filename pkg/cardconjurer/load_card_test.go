@@ -0,0 +1,196 @@
+package cardconjurer
+
+import (
+	"cardconjurer-automation/pkg/common"
+	"cardconjurer-automation/pkg/decklist_parser"
+	"context"
+	"errors"
+	"testing"
+)
+
+var solRingOptions = []selectOption{
+	{Value: "0", Text: "Sol Ring (LTC #3)", Released: "2023-06-23"},
+	{Value: "1", Text: "Sol Ring (C21 #263)", Released: "2021-04-23"},
+	{Value: "2", Text: "Sol Ring (CMR #472)", Released: "2020-11-20"},
+}
+
+func TestLoadCard(t *testing.T) {
+	solRing := &decklist_parser.Card{Count: 1, Name: "Sol Ring", Set: "C21", CollectorNumber: "263"}
+
+	tests := []struct {
+		name        string
+		options     []selectOption
+		resolution  *resolution
+		fallback    FallbackPolicy
+		preferred   []string
+		missing     string
+		want        string
+		wantResolve bool
+		wantErr     bool
+	}{
+		{
+			name:    "matching printing",
+			options: solRingOptions,
+			want:    "Sol Ring (C21 #263)",
+		},
+		{
+			name:       "resolved printing",
+			options:    solRingOptions,
+			resolution: &resolution{Text: "Sol Ring (CMR #472)", Value: "2"},
+			want:       "Sol Ring (CMR #472)",
+		},
+		{
+			name:       "pinned printing",
+			options:    solRingOptions,
+			resolution: &resolution{Text: "Sol Ring (LTC #3)", Value: "0", Pinned: true},
+			want:       "Sol Ring (LTC #3)",
+		},
+		{
+			name:       "pinned printing gone",
+			options:    solRingOptions,
+			resolution: &resolution{Text: "Sol Ring (SLD #9999)", Value: "7", Pinned: true},
+			want:       "Sol Ring (C21 #263)",
+		},
+		{
+			name:        "unknown printing",
+			options:     solRingOptions[:1],
+			wantResolve: true,
+			wantErr:     true,
+		},
+		{
+			name:     "unknown printing with fallback",
+			options:  solRingOptions[:1],
+			fallback: FallbackAny,
+			want:     "Sol Ring (LTC #3)",
+		},
+		{
+			name:     "newest fallback by release date",
+			options:  []selectOption{solRingOptions[2], solRingOptions[0]},
+			fallback: FallbackNewest,
+			want:     "Sol Ring (LTC #3)",
+		},
+		{
+			name: "newest fallback without release dates",
+			options: []selectOption{
+				{Value: "0", Text: "Sol Ring (LTC #3)"},
+				{Value: "2", Text: "Sol Ring (CMR #472)"},
+			},
+			fallback: FallbackNewest,
+			want:     "Sol Ring (LTC #3)",
+		},
+		{
+			name:      "preferred fallback in set order",
+			options:   []selectOption{solRingOptions[0], solRingOptions[2]},
+			fallback:  FallbackPreferred,
+			preferred: []string{"sld", "cmr", "ltc"},
+			want:      "Sol Ring (CMR #472)",
+		},
+		{
+			name:        "preferred fallback without preferred printing",
+			options:     []selectOption{solRingOptions[0], solRingOptions[2]},
+			fallback:    FallbackPreferred,
+			preferred:   []string{"sld"},
+			wantResolve: true,
+			wantErr:     true,
+		},
+		{
+			name:        "no search results",
+			wantResolve: true,
+			wantErr:     true,
+		},
+		{
+			name:    "missing import field",
+			options: solRingOptions,
+			missing: DefaultSelectors().ImportName,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := newFakeDriver().
+				on("length > 0", len(tt.options) > 0).
+				on("released_at", tt.options)
+			delete(d.elements, tt.missing)
+
+			w := newTestWorker(t, &Config{Fallback: tt.fallback, PreferredSets: tt.preferred}, d)
+			if tt.resolution != nil {
+				w.resolutions = map[common.CardInfo]resolution{solRing: *tt.resolution}
+			}
+
+			option, err := w.loadCard(solRing, context.Background())
+			if tt.wantErr {
+				var resolveErr *ResolveError
+				if err == nil {
+					t.Fatalf("expected an error, selected %q", option.Text)
+				} else if errors.As(err, &resolveErr) != tt.wantResolve {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if option.Text != tt.want {
+				t.Errorf("selected %q, expected %q", option.Text, tt.want)
+			}
+			if call := "set " + DefaultSelectors().ImportIndex + "=" + option.Value; !d.called(call) {
+				t.Errorf("option was not selected in the dropdown, calls: %v", d.calls)
+			}
+		})
+	}
+}
+
+func TestSearchCardEntersName(t *testing.T) {
+	sel := DefaultSelectors()
+	d := newFakeDriver().
+		on("length > 0", true).
+		on("released_at", solRingOptions)
+	w := newTestWorker(t, &Config{}, d)
+
+	options, err := w.searchCard(context.Background(), &decklist_parser.Card{Name: "Sol Ring"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(options) != len(solRingOptions) || options[0].Released != "2023-06-23" {
+		t.Errorf("unexpected search results %+v", options)
+	}
+	for _, call := range []string{"set " + sel.ImportName + "=Sol Ring", "keys " + sel.ImportName} {
+		if !d.called(call) {
+			t.Errorf("missing call %q, calls: %v", call, d.calls)
+		}
+	}
+}
+
+func TestUnknownFrameIsNotRetried(t *testing.T) {
+	frames := []selectOption{{Value: "Seventh", Text: "7th Edition"}, {Value: "M15", Text: "M15"}}
+	pipeline, err := buildPipeline([]string{StepImport})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range []struct {
+		frame        string
+		wantStatus   CardStatus
+		wantAttempts int
+	}{
+		{frame: "Eighth", wantStatus: StatusFailed, wantAttempts: 1},
+		{frame: "7th edition", wantStatus: StatusSucceeded, wantAttempts: 1},
+	} {
+		t.Run(tt.frame, func(t *testing.T) {
+			d := newFakeDriver().
+				on(jsString(DefaultSelectors().AutoFrame+" option"), frames).
+				on("length > 0", true).
+				on("released_at", solRingOptions)
+			w := newTestWorker(t, &Config{Frame: tt.frame, Retry: RetryPolicy{MaxAttempts: 3}}, d)
+			w.pipeline = pipeline
+			w.browserCtx = context.Background()
+
+			result := w.processCard(context.Background(), context.Background(), &decklist_parser.Card{Count: 1, Name: "Sol Ring", Set: "C21", CollectorNumber: "263"})
+			if result.Status != tt.wantStatus || result.Attempts != tt.wantAttempts {
+				t.Errorf("%s after %d attempt(s), expected %s after %d: %s", result.Status, result.Attempts, tt.wantStatus, tt.wantAttempts, result.Error)
+			}
+		})
+	}
+}
@@ -0,0 +1,202 @@
+package cardconjurer
+
+import (
+	"cardconjurer-automation/pkg/common"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"sync"
+	"time"
+)
+
+const lockVersion = 1
+
+// LockFileName is the name of the lock file in the project directory.
+const LockFileName = "deck.lock"
+
+// Pin records the Card Conjurer printing a deck entry was rendered from.
+type Pin struct {
+	Card        string    `json:"card"`
+	OptionText  string    `json:"option_text"`
+	OptionValue string    `json:"option_value"`
+	Frame       string    `json:"frame"`
+	ArtworkHash string    `json:"artwork_hash,omitempty"`
+	Pinned      time.Time `json:"pinned"`
+}
+
+// LockFile pins the printing of every deck entry, so later runs render the same printing
+// without searching again. Entries are keyed by printing id like the manifest.
+type LockFile struct {
+	Version int            `json:"version"`
+	Cards   map[string]Pin `json:"cards"`
+
+	path string
+	mu   sync.Mutex
+}
+
+// LoadLockFile reads the lock file, a missing file returns an empty one.
+func LoadLockFile(path string) (*LockFile, error) {
+	l := &LockFile{
+		Version: lockVersion,
+		Cards:   make(map[string]Pin),
+		path:    path,
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return l, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(data, l); err != nil {
+		return nil, err
+	}
+	if l.Cards == nil {
+		l.Cards = make(map[string]Pin)
+	}
+	return l, nil
+}
+
+func (l *LockFile) pin(id string) (Pin, bool) {
+	if l == nil {
+		return Pin{}, false
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	pin, ok := l.Cards[id]
+	return pin, ok
+}
+
+// set stores the pin and reports whether it changed. An unchanged pin keeps its timestamp,
+// so the lock file only changes when a printing does.
+func (l *LockFile) set(id string, pin Pin) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if old, ok := l.Cards[id]; ok && old.samePrinting(pin) {
+		return false
+	}
+	l.Cards[id] = pin
+	return true
+}
+
+func (p Pin) samePrinting(other Pin) bool {
+	return p.Card == other.Card &&
+		p.OptionText == other.OptionText &&
+		p.OptionValue == other.OptionValue &&
+		p.Frame == other.Frame &&
+		p.ArtworkHash == other.ArtworkHash
+}
+
+// Save writes the lock file. The lock is held until the file is written, so concurrent
+// saves can't replace a newer snapshot with an older one.
+func (l *LockFile) Save() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	data, err := json.MarshalIndent(l, "", "  ")
+	if err != nil {
+		return err
+	}
+	return common.WriteFileAtomic(l.path, data, 0644)
+}
+
+// pinnedResolutions takes the printing of every card with a pin from the lock file and returns
+// the cards that still need to be searched. Pins whose frame or artwork changed are reused,
+// but reported, as the printing is still valid.
+func (cc *CardConjurer) pinnedResolutions(cards []common.CardInfo) []common.CardInfo {
+	var unpinned []common.CardInfo
+	for _, card := range cards {
+		pin, ok := cc.lock.pin(card.GetPrintingID())
+		if !ok {
+			unpinned = append(unpinned, card)
+			continue
+		}
+
+		if frame := cc.config.frameFor(card); frame != pin.Frame {
+			cc.logger.Warnf("Card '%s' was pinned with frame '%s', rendering with '%s'", card.GetFullName(), pin.Frame, frame)
+		}
+		if hash, err := hashFile(cc.config.artworkPath(card)); err == nil && hash != pin.ArtworkHash {
+			cc.logger.Warnf("Artwork of card '%s' changed since it was pinned", card.GetFullName())
+		}
+		cc.resolutions[card] = resolution{Text: pin.OptionText, Value: pin.OptionValue, Pinned: true}
+	}
+
+	if pinned := len(cards) - len(unpinned); pinned > 0 {
+		cc.logger.Infof("Using %d pinned printing(s) from %s", pinned, cc.config.LockPath)
+	}
+	return unpinned
+}
+
+// pin records the printing the card was rendered from and saves the lock file if the pin changed.
+func (cc *CardConjurer) pin(card common.CardInfo, option selectOption) {
+	if cc.lock == nil {
+		return
+	}
+
+	artworkHash, err := hashFile(cc.config.artworkPath(card))
+	if err != nil {
+		cc.logger.Warnw("Could not hash artwork for lock file", "card", card.GetFullName(), "error", err)
+	}
+	changed := cc.lock.set(card.GetPrintingID(), Pin{
+		Card:        card.GetFullName(),
+		OptionText:  option.Text,
+		OptionValue: option.Value,
+		Frame:       cc.config.frameFor(card),
+		ArtworkHash: artworkHash,
+		Pinned:      time.Now(),
+	})
+	if !changed {
+		return
+	}
+	if err := cc.lock.Save(); err != nil {
+		cc.logger.Errorw("Could not write lock file", "error", err)
+	}
+}
+
+// RefreshPins searches all cards in Card Conjurer again and replaces their pins without
+// rendering anything. Cards that can't be resolved keep their pin and are reported as failed.
+func (cc *CardConjurer) RefreshPins(ctx context.Context) error {
+	if cc.lock == nil {
+		return errors.New("no lock file configured")
+	}
+
+	cc.started = time.Now()
+	defer func() {
+		cc.finished = time.Now()
+	}()
+
+	stopSite, err := cc.config.ServeSite(cc.logger)
+	if err != nil {
+		return err
+	}
+	defer stopSite()
+	defer cc.saveCache()
+
+	// Pins don't depend on the frame, only the site is checked
+	if err := cc.preflight(ctx, nil); err != nil {
+		return err
+	}
+
+	workers := make([]*worker, cc.config.Workers)
+	for i := range workers {
+		workers[i] = cc.newWorker(i, cc.logger, nil)
+		defer workers[i].restartBrowser()
+	}
+
+	for _, card := range cc.resolveCards(ctx, ctx, workers, cc.cards, false) {
+		res := cc.resolutions[card]
+		cc.pin(card, selectOption{Value: res.Value, Text: res.Text})
+
+		result := newCardReport(card, -1)
+		result.Status = StatusSucceeded
+		result.Resolved = res.Text
+		result.Fallback = string(res.Fallback)
+		result.Reason = "pinned " + res.Text
+		cc.resultsMu.Lock()
+		cc.results[card] = result
+		cc.resultsMu.Unlock()
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+package cardconjurer
+
+import (
+	"fmt"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestLockFileKeepsUnchangedPins(t *testing.T) {
+	lock, err := LoadLockFile(filepath.Join(t.TempDir(), LockFileName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	pinned := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	pin := Pin{Card: "Sol Ring (C21) 263", OptionText: "Sol Ring (C21 #263)", OptionValue: "12", Frame: "Commander", Pinned: pinned}
+
+	if !lock.set("sol_ring_c21_263", pin) {
+		t.Fatal("a new pin must be reported as changed")
+	}
+	again := pin
+	again.Pinned = pinned.Add(time.Hour)
+	if lock.set("sol_ring_c21_263", again) {
+		t.Error("an unchanged pin was reported as changed")
+	}
+	if got, _ := lock.pin("sol_ring_c21_263"); !got.Pinned.Equal(pinned) {
+		t.Errorf("unchanged pin got a new timestamp %s", got.Pinned)
+	}
+
+	again.ArtworkHash = "abc"
+	if !lock.set("sol_ring_c21_263", again) {
+		t.Error("a pin with new artwork must be reported as changed")
+	}
+	if got, _ := lock.pin("sol_ring_c21_263"); !got.Pinned.Equal(again.Pinned) {
+		t.Errorf("changed pin kept the old timestamp %s", got.Pinned)
+	}
+}
+
+func TestLockFileConcurrentSaves(t *testing.T) {
+	path := filepath.Join(t.TempDir(), LockFileName)
+	lock, err := LoadLockFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			lock.set(fmt.Sprintf("card_%d", i), Pin{Card: fmt.Sprintf("Card %d", i)})
+			if err := lock.Save(); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	saved, err := LoadLockFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(saved.Cards) != 20 {
+		t.Errorf("the last save must contain all 20 pins, got %d", len(saved.Cards))
+	}
+}
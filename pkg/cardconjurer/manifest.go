@@ -0,0 +1,186 @@
+package cardconjurer
+
+import (
+	"cardconjurer-automation/pkg/common"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+const manifestVersion = 1
+
+// ManifestInputs are everything that influences the rendered image of a card.
+type ManifestInputs struct {
+	Printing string `json:"printing"`
+	// Option is the pinned Card Conjurer printing, if pinning is enabled
+	Option      string   `json:"option,omitempty"`
+	Frame       string   `json:"frame"`
+	ArtworkHash string   `json:"artwork_hash,omitempty"`
+	Pipeline    []string `json:"pipeline"`
+	// Capture is the capture mode, the canvas and the downloaded image differ
+	Capture string `json:"capture"`
+}
+
+func (i ManifestInputs) equal(other ManifestInputs) bool {
+	return i.Printing == other.Printing &&
+		i.Option == other.Option &&
+		i.Frame == other.Frame &&
+		i.ArtworkHash == other.ArtworkHash &&
+		slices.Equal(i.Pipeline, other.Pipeline) &&
+		i.Capture == other.Capture
+}
+
+type ManifestEntry struct {
+	ManifestInputs
+	OutputPath string    `json:"output_path"`
+	Rendered   time.Time `json:"rendered"`
+}
+
+// Manifest records per printing which inputs produced the card in the output folder,
+// so unchanged cards can be skipped on the next run.
+type Manifest struct {
+	Version int                      `json:"version"`
+	Cards   map[string]ManifestEntry `json:"cards"`
+
+	path string
+	mu   sync.Mutex
+}
+
+// LoadManifest reads the manifest, a missing file returns an empty manifest.
+func LoadManifest(path string) (*Manifest, error) {
+	m := &Manifest{
+		Version: manifestVersion,
+		Cards:   make(map[string]ManifestEntry),
+		path:    path,
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return m, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(data, m); err != nil {
+		return nil, err
+	}
+	if m.Cards == nil {
+		m.Cards = make(map[string]ManifestEntry)
+	}
+	return m, nil
+}
+
+// upToDate checks whether the card was rendered with the same inputs to outputPath and the file still exists.
+// A card rendered under another name or into another folder is not up to date.
+func (m *Manifest) upToDate(id string, inputs ManifestInputs, outputPath string) (ManifestEntry, bool) {
+	m.mu.Lock()
+	entry, ok := m.Cards[id]
+	m.mu.Unlock()
+
+	if !ok || !entry.equal(inputs) || entry.OutputPath != outputPath {
+		return entry, false
+	}
+	if _, err := os.Stat(entry.OutputPath); err != nil {
+		return entry, false
+	}
+	return entry, true
+}
+
+func (m *Manifest) set(id string, entry ManifestEntry) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.Cards[id] = entry
+}
+
+// Save writes the manifest. The lock is held until the file is written, so concurrent
+// saves can't replace a newer snapshot with an older one.
+func (m *Manifest) Save() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	return common.WriteFileAtomic(m.path, data, 0644)
+}
+
+// ForceSet selects the cards that are rendered even if the manifest says they are up to date.
+// It implements flag.Value: "--force" forces all cards, "--force=Card A,Card B" selected ones.
+// As a boolean flag, card names must be joined with '=', "--force Card A" forces all cards
+// and leaves "Card A" as an argument.
+type ForceSet struct {
+	All   bool
+	Cards []string
+}
+
+func (f *ForceSet) String() string {
+	if f == nil {
+		return ""
+	}
+	if f.All {
+		return "true"
+	}
+	return strings.Join(f.Cards, ",")
+}
+
+func (f *ForceSet) Set(s string) error {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "true", "all":
+		f.All = true
+	case "false", "":
+		f.All = false
+	default:
+		for _, name := range strings.Split(s, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				f.Cards = append(f.Cards, name)
+			}
+		}
+	}
+	return nil
+}
+
+func (f *ForceSet) IsBoolFlag() bool {
+	return true
+}
+
+// forced matches the card by name, full name or printing id.
+func (f *ForceSet) forced(card common.CardInfo) bool {
+	if f == nil {
+		return false
+	}
+	if f.All {
+		return true
+	}
+	for _, name := range f.Cards {
+		if strings.EqualFold(name, card.GetName()) || strings.EqualFold(name, card.GetFullName()) || name == card.GetPrintingID() {
+			return true
+		}
+	}
+	return false
+}
+
+// hashFile returns the hex encoded SHA-256 of the file, "" if it does not exist.
+func hashFile(path string) (string, error) {
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
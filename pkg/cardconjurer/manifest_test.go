@@ -0,0 +1,81 @@
+package cardconjurer
+
+import (
+	"cardconjurer-automation/pkg/common"
+	"cardconjurer-automation/pkg/decklist_parser"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+func TestManifestUpToDate(t *testing.T) {
+	dir := t.TempDir()
+	card := &decklist_parser.Card{Count: 1, Name: "Sol Ring", Set: "C21", CollectorNumber: "263"}
+	cfg := &Config{ProjectName: "test", OutputCardsFolder: dir, Naming: common.NamingPrinting}
+	byName := Config{ProjectName: "test", OutputCardsFolder: dir, Naming: common.NamingName}
+	output := cfg.outputPath(card)
+	// Both files exist, so only the stored path decides
+	for _, path := range []string{output, byName.outputPath(card)} {
+		if err := os.WriteFile(path, []byte("card"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	inputs := ManifestInputs{Printing: "Sol Ring (C21) 263", Frame: "Commander", Pipeline: DefaultPipeline, Capture: CaptureCanvas}
+
+	manifest, err := LoadManifest(filepath.Join(dir, "manifest.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	manifest.set(card.GetPrintingID(), ManifestEntry{ManifestInputs: inputs, OutputPath: output})
+
+	changed := inputs
+	changed.Capture = CaptureDownload
+	for _, tt := range []struct {
+		name   string
+		inputs ManifestInputs
+		config Config
+		want   bool
+	}{
+		{"same inputs", inputs, *cfg, true},
+		{"other capture mode", changed, *cfg, false},
+		{"other naming scheme", inputs, byName, false},
+		{"other output folder", inputs, Config{ProjectName: "test", OutputCardsFolder: t.TempDir(), Naming: common.NamingPrinting}, false},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, ok := manifest.upToDate(card.GetPrintingID(), tt.inputs, tt.config.outputPath(card)); ok != tt.want {
+				t.Errorf("up to date = %v, expected %v", ok, tt.want)
+			}
+		})
+	}
+}
+
+func TestManifestConcurrentSaves(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "manifest.json")
+	manifest, err := LoadManifest(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			manifest.set(fmt.Sprintf("card_%d", i), ManifestEntry{OutputPath: fmt.Sprintf("card_%d.png", i)})
+			if err := manifest.Save(); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	saved, err := LoadManifest(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(saved.Cards) != 20 {
+		t.Errorf("the last save must contain all 20 entries, got %d", len(saved.Cards))
+	}
+}
@@ -0,0 +1,167 @@
+package cardconjurer
+
+import (
+	"cardconjurer-automation/pkg/common"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+// DefaultMatchThreshold is used if Config.MatchThreshold is not set. It accepts small
+// differences in the name, but requires the set and at least the collector number without suffix.
+const DefaultMatchThreshold = 90
+
+// Weights of the parts of a printing, a perfect match scores 100
+const (
+	nameWeight            = 60
+	setWeight             = 25
+	collectorNumberWeight = 15
+	// collectorBaseWeight is scored if only the suffix of the collector number differs, e.g. 12 and 12a
+	collectorBaseWeight = 10
+)
+
+// normalizeReplacer maps typographic variants to the characters used in decklists
+var normalizeReplacer = strings.NewReplacer(
+	"‘", "'", "’", "'", "ʼ", "'", "`", "'", "´", "'",
+	"“", `"`, "”", `"`,
+	"‐", "-", "‑", "-", "–", "-", "—", "-",
+	" ", " ", "…", "...",
+	"á", "a", "à", "a", "â", "a", "ä", "a", "ã", "a",
+	"é", "e", "è", "e", "ê", "e", "ë", "e",
+	"í", "i", "î", "i", "ï", "i",
+	"ó", "o", "ô", "o", "ö", "o",
+	"ú", "u", "û", "u", "ü", "u",
+	"ñ", "n", "æ", "ae",
+)
+
+// normalizeName lowercases the name, unifies punctuation and accents and collapses whitespace.
+func normalizeName(s string) string {
+	s = normalizeReplacer.Replace(strings.ToLower(s))
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// splitCollectorNumber normalizes a collector number into the number without leading
+// zeros and its suffix, e.g. "007a" becomes "7" and "a", "12★" becomes "12" and "★".
+func splitCollectorNumber(s string) (number, suffix string) {
+	s = strings.ToLower(strings.Join(strings.Fields(s), ""))
+	i := strings.IndexFunc(s, func(r rune) bool { return !unicode.IsDigit(r) })
+	if i < 0 {
+		i = len(s)
+	}
+	number = strings.TrimLeft(s[:i], "0")
+	if number == "" && i > 0 {
+		number = "0"
+	}
+	return number, s[i:]
+}
+
+// printingMatch is a search result with its score against a card.
+type printingMatch struct {
+	option selectOption
+	score  float64
+}
+
+// scoreOption rates how well the search result matches the card, from 0 to 100.
+// Missing set or collector number of the card count as matching, without a set the name
+// has to match exactly.
+func scoreOption(card common.CardInfo, option selectOption) float64 {
+	name, set, collectorNumber, ok := parseOption(option.Text)
+	if !ok {
+		name = option.Text
+	}
+
+	wantName, gotName := normalizeName(card.GetName()), normalizeName(name)
+	if card.GetSet() == "" {
+		// Without a set nothing but the name tells a misspelling from a different card
+		if wantName != gotName {
+			return 0
+		}
+		return nameWeight + setWeight + collectorNumberWeight
+	}
+
+	var score float64
+	if wantName == gotName {
+		score = nameWeight
+	} else {
+		longest := max(len([]rune(wantName)), len([]rune(gotName)))
+		if longest > 0 {
+			score = nameWeight * (1 - float64(levenshtein(wantName, gotName))/float64(longest))
+		}
+	}
+
+	if !ok || !strings.EqualFold(strings.TrimSpace(card.GetSet()), set) {
+		return score
+	}
+	score += setWeight
+
+	if card.GetCollectorNumber() == "" {
+		return score + collectorNumberWeight
+	}
+	wantNumber, wantSuffix := splitCollectorNumber(card.GetCollectorNumber())
+	gotNumber, gotSuffix := splitCollectorNumber(collectorNumber)
+	switch {
+	case wantNumber == gotNumber && wantSuffix == gotSuffix:
+		score += collectorNumberWeight
+	case wantNumber == gotNumber:
+		score += collectorBaseWeight
+	}
+	return score
+}
+
+// rankOptions scores every search result against the card, best match first.
+// Equal scores keep the order of the search results.
+func rankOptions(card common.CardInfo, options []selectOption) []printingMatch {
+	matches := make([]printingMatch, 0, len(options))
+	for _, option := range options {
+		matches = append(matches, printingMatch{option: option, score: scoreOption(card, option)})
+	}
+	slices.SortStableFunc(matches, func(a, b printingMatch) int {
+		switch {
+		case a.score > b.score:
+			return -1
+		case a.score < b.score:
+			return 1
+		}
+		return 0
+	})
+	return matches
+}
+
+// matchOption returns the best search result for the card if it scores at least threshold.
+// Several different printings sharing the best score are ambiguous, unless the card leaves
+// the set or collector number open and accepts any of them.
+func matchOption(card common.CardInfo, options []selectOption, threshold float64) (selectOption, []string, bool) {
+	ranked := rankOptions(card, options)
+	if len(ranked) == 0 || ranked[0].score < threshold {
+		return selectOption{}, nil, false
+	}
+
+	best := ranked[0]
+	if card.GetSet() == "" || card.GetCollectorNumber() == "" {
+		return best.option, nil, true
+	}
+
+	ambiguous := []string{best.option.Text}
+	for _, m := range ranked[1:] {
+		if m.score < best.score {
+			break
+		}
+		if normalizeName(m.option.Text) != normalizeName(best.option.Text) {
+			ambiguous = append(ambiguous, m.option.Text)
+		}
+	}
+	if len(ambiguous) > 1 {
+		return selectOption{}, ambiguous, false
+	}
+	return best.option, nil, true
+}
+
+// closestOptions returns the texts of the n best ranked search results.
+func closestOptions(card common.CardInfo, options []selectOption, n int) []string {
+	ranked := rankOptions(card, options)
+	texts := make([]string, 0, n)
+	for _, m := range ranked[:min(n, len(ranked))] {
+		texts = append(texts, m.option.Text)
+	}
+	return texts
+}
@@ -0,0 +1,147 @@
+package cardconjurer
+
+import (
+	"cardconjurer-automation/pkg/decklist_parser"
+	"slices"
+	"testing"
+)
+
+func TestScoreOption(t *testing.T) {
+	tests := []struct {
+		name   string
+		card   decklist_parser.Card
+		option string
+		want   float64
+	}{
+		{"exact printing", decklist_parser.Card{Name: "Sol Ring", Set: "C21", CollectorNumber: "263"}, "Sol Ring (C21 #263)", 100},
+		{"set case", decklist_parser.Card{Name: "Sol Ring", Set: "c21", CollectorNumber: "263"}, "Sol Ring (C21 #263)", 100},
+		{"leading zeros", decklist_parser.Card{Name: "Sol Ring", Set: "LTC", CollectorNumber: "003"}, "Sol Ring (LTC #3)", 100},
+		{"other suffix", decklist_parser.Card{Name: "Sol Ring", Set: "C21", CollectorNumber: "263"}, "Sol Ring (C21 #263a)", 95},
+		{"other number", decklist_parser.Card{Name: "Sol Ring", Set: "C21", CollectorNumber: "263"}, "Sol Ring (C21 #264)", 85},
+		{"other set", decklist_parser.Card{Name: "Sol Ring", Set: "C21", CollectorNumber: "263"}, "Sol Ring (CMR #263)", 60},
+		{"set without number", decklist_parser.Card{Name: "Sol Ring", Set: "C21"}, "Sol Ring (C21 #1)", 100},
+		{"typographic apostrophe", decklist_parser.Card{Name: "Urza's Saga", Set: "MH2", CollectorNumber: "259"}, "Urza’s Saga (MH2 #259)", 100},
+		{"misspelled name", decklist_parser.Card{Name: "Lightning Bolts", Set: "M10", CollectorNumber: "146"}, "Lightning Bolt (M10 #146)", 100 - 60.0/15},
+		{"name only", decklist_parser.Card{Name: "Sol Ring"}, "Sol Ring (C21 #263)", 100},
+		{"name only with accents", decklist_parser.Card{Name: "Seance"}, "Séance (SHM #18)", 100},
+		{"name only, other card", decklist_parser.Card{Name: "Sol Ring"}, "Sol Talisman (MH1 #238)", 0},
+		{"name only, misspelled", decklist_parser.Card{Name: "Lightning Bolts"}, "Lightning Bolt (M10 #146)", 0},
+		{"unparsed option", decklist_parser.Card{Name: "Sol Ring"}, "Sol Ring", 100},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := scoreOption(&tt.card, selectOption{Text: tt.option})
+			if diff := got - tt.want; diff > 1e-9 || diff < -1e-9 {
+				t.Errorf("score = %v, expected %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMatchOption(t *testing.T) {
+	tests := []struct {
+		name          string
+		card          decklist_parser.Card
+		options       []string
+		want          string
+		wantAmbiguous []string
+	}{
+		{
+			name:    "best printing",
+			card:    decklist_parser.Card{Name: "Sol Ring", Set: "C21", CollectorNumber: "263"},
+			options: []string{"Sol Ring (LTC #3)", "Sol Ring (C21 #263)", "Sol Ring (C21 #263a)"},
+			want:    "Sol Ring (C21 #263)",
+		},
+		{
+			name:          "same score, different printings",
+			card:          decklist_parser.Card{Name: "Plains", Set: "UST", CollectorNumber: "212"},
+			options:       []string{"Plains (UST #212a)", "Plains (UST #212b)"},
+			wantAmbiguous: []string{"Plains (UST #212a)", "Plains (UST #212b)"},
+		},
+		{
+			name:    "same score, same printing listed twice",
+			card:    decklist_parser.Card{Name: "Sol Ring", Set: "C21", CollectorNumber: "263"},
+			options: []string{"Sol Ring (C21 #263)", "Sol  Ring (C21 #263)"},
+			want:    "Sol Ring (C21 #263)",
+		},
+		{
+			name:    "open collector number accepts the first",
+			card:    decklist_parser.Card{Name: "Plains", Set: "UST"},
+			options: []string{"Plains (UST #212a)", "Plains (UST #212b)"},
+			want:    "Plains (UST #212a)",
+		},
+		{
+			name:    "name only",
+			card:    decklist_parser.Card{Name: "Sol Ring"},
+			options: []string{"Sol Talisman (MH1 #238)", "Sol Ring (C21 #263)"},
+			want:    "Sol Ring (C21 #263)",
+		},
+		{
+			name:    "name only, no exact name",
+			card:    decklist_parser.Card{Name: "Sol Rings"},
+			options: []string{"Sol Ring (C21 #263)"},
+		},
+		{
+			name:    "below threshold",
+			card:    decklist_parser.Card{Name: "Sol Ring", Set: "C21", CollectorNumber: "263"},
+			options: []string{"Sol Ring (CMR #472)"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			options := make([]selectOption, 0, len(tt.options))
+			for _, text := range tt.options {
+				options = append(options, selectOption{Text: text})
+			}
+			got, ambiguous, ok := matchOption(&tt.card, options, DefaultMatchThreshold)
+			if ok != (tt.want != "") || got.Text != tt.want {
+				t.Errorf("matched %q (%v), expected %q", got.Text, ok, tt.want)
+			}
+			if !slices.Equal(ambiguous, tt.wantAmbiguous) {
+				t.Errorf("ambiguous %q, expected %q", ambiguous, tt.wantAmbiguous)
+			}
+		})
+	}
+}
+
+func TestSplitCollectorNumber(t *testing.T) {
+	tests := []struct {
+		in, number, suffix string
+	}{
+		{"263", "263", ""},
+		{"007", "7", ""},
+		{"007a", "7", "a"},
+		{"12★", "12", "★"},
+		{"12 A", "12", "a"},
+		{"000", "0", ""},
+		{"", "", ""},
+		{"S12", "", "s12"},
+	}
+	for _, tt := range tests {
+		number, suffix := splitCollectorNumber(tt.in)
+		if number != tt.number || suffix != tt.suffix {
+			t.Errorf("splitCollectorNumber(%q) = %q, %q, expected %q, %q", tt.in, number, suffix, tt.number, tt.suffix)
+		}
+	}
+}
+
+func TestNormalizeName(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"Sol Ring", "sol ring"},
+		{"  Sol   Ring ", "sol ring"},
+		{"Séance", "seance"},
+		{"Jötun Grunt", "jotun grunt"},
+		{"Ærathi Berserker", "aerathi berserker"},
+		{"Lim-Dûl's Vault", "lim-dul's vault"},
+		{"Urza’s Saga", "urza's saga"},
+		{"Borrowing 100,000 Arrows", "borrowing 100,000 arrows"},
+		{"Circle of Protection—Red", "circle of protection-red"},
+	}
+	for _, tt := range tests {
+		if got := normalizeName(tt.in); got != tt.want {
+			t.Errorf("normalizeName(%q) = %q, expected %q", tt.in, got, tt.want)
+		}
+	}
+}
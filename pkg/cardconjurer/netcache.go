@@ -0,0 +1,253 @@
+package cardconjurer
+
+import (
+	"cardconjurer-automation/pkg/common"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/chromedp/cdproto/network"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// NetworkCacheMode decides how the browsers use the network cache.
+type NetworkCacheMode string
+
+const (
+	// NetworkCachePassthrough leaves all requests to the network, the cache is not used
+	NetworkCachePassthrough NetworkCacheMode = "passthrough"
+	// NetworkCacheRecord lets requests through to the network and stores every response
+	NetworkCacheRecord NetworkCacheMode = "record"
+	// NetworkCacheReplay answers all requests from the cache, requests that were not
+	// recorded fail as if the browser was offline
+	NetworkCacheReplay NetworkCacheMode = "replay"
+)
+
+func ParseNetworkCacheMode(s string) (NetworkCacheMode, error) {
+	switch mode := NetworkCacheMode(strings.ToLower(strings.TrimSpace(s))); mode {
+	case "":
+		return NetworkCachePassthrough, nil
+	case NetworkCachePassthrough, NetworkCacheRecord, NetworkCacheReplay:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("unknown network cache mode %q, expected %q, %q or %q", s, NetworkCachePassthrough, NetworkCacheRecord, NetworkCacheReplay)
+	}
+}
+
+const networkCacheVersion = 1
+
+// networkCacheIndex is the name of the index in the cache directory, bodies are stored
+// next to it in blobs/, named by their SHA-256.
+const networkCacheIndex = "index.json"
+
+// Headers that describe the transfer of the recorded body, not the body itself
+var transferHeaders = []string{"Content-Encoding", "Content-Length", "Transfer-Encoding"}
+
+// CacheEntry is a recorded request and its response, modelled after a HAR entry.
+type CacheEntry struct {
+	Request  CacheRequest  `json:"request"`
+	Response CacheResponse `json:"response"`
+	Recorded time.Time     `json:"recorded"`
+}
+
+type CacheRequest struct {
+	Method string `json:"method"`
+	URL    string `json:"url"`
+}
+
+type CacheResponse struct {
+	Status     int           `json:"status"`
+	StatusText string        `json:"status_text,omitempty"`
+	Headers    []CacheHeader `json:"headers"`
+	Content    CacheContent  `json:"content"`
+}
+
+type CacheHeader struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// CacheContent points to the body in the blobs directory.
+type CacheContent struct {
+	Size     int    `json:"size"`
+	MimeType string `json:"mime_type,omitempty"`
+	Hash     string `json:"hash"`
+}
+
+// NetworkCache records the responses the pages receive and replays them in later runs.
+// Entries are keyed by request method, url and a hash of the post data. Identical bodies,
+// e.g. the same frame image under different urls, are stored once.
+type NetworkCache struct {
+	Version int                   `json:"version"`
+	Entries map[string]CacheEntry `json:"entries"`
+
+	dir   string
+	mode  NetworkCacheMode
+	mu    sync.Mutex
+	dirty bool
+}
+
+// LoadNetworkCache reads the cache index in dir, a missing index returns an empty cache.
+func LoadNetworkCache(dir string, mode NetworkCacheMode) (*NetworkCache, error) {
+	c := &NetworkCache{
+		Version: networkCacheVersion,
+		Entries: make(map[string]CacheEntry),
+		dir:     dir,
+		mode:    mode,
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, networkCacheIndex))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	if err == nil {
+		if err := json.Unmarshal(data, c); err != nil {
+			return nil, err
+		}
+		if c.Entries == nil {
+			c.Entries = make(map[string]CacheEntry)
+		}
+	}
+
+	if mode == NetworkCacheRecord {
+		if err := os.MkdirAll(filepath.Join(dir, "blobs"), 0755); err != nil {
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+// Len returns the number of recorded requests.
+func (c *NetworkCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.Entries)
+}
+
+func cacheKey(req *network.Request) string {
+	url, _, _ := strings.Cut(req.URL, "#")
+	key := req.Method + " " + url
+	if req.HasPostData {
+		var postData strings.Builder
+		for _, entry := range req.PostDataEntries {
+			postData.WriteString(entry.Bytes)
+		}
+		key += " " + hashBytes([]byte(postData.String()))
+	}
+	return key
+}
+
+func hashBytes(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func (c *NetworkCache) blobPath(hash string) string {
+	return filepath.Join(c.dir, "blobs", hash)
+}
+
+// serve answers the request from the cache in replay mode.
+func (c *NetworkCache) serve(req *network.Request) *fetchResponse {
+	if c.mode != NetworkCacheReplay {
+		return nil
+	}
+
+	c.mu.Lock()
+	entry, ok := c.Entries[cacheKey(req)]
+	c.mu.Unlock()
+	if !ok {
+		return &fetchResponse{Error: network.ErrorReasonInternetDisconnected}
+	}
+
+	body, err := os.ReadFile(c.blobPath(entry.Response.Content.Hash))
+	if err != nil {
+		return &fetchResponse{Error: network.ErrorReasonFailed}
+	}
+	resp := &fetchResponse{
+		Status:     entry.Response.Status,
+		StatusText: entry.Response.StatusText,
+		Headers:    make(map[string]string, len(entry.Response.Headers)),
+		Body:       body,
+	}
+	for _, header := range entry.Response.Headers {
+		resp.Headers[header.Name] = header.Value
+	}
+	return resp
+}
+
+// record stores the response in record mode, the body is only written if it is new.
+func (c *NetworkCache) record(req *network.Request, resp *fetchResponse) error {
+	if c.mode != NetworkCacheRecord {
+		return nil
+	}
+
+	hash := hashBytes(resp.Body)
+	if _, err := os.Stat(c.blobPath(hash)); errors.Is(err, os.ErrNotExist) {
+		if err := common.WriteFileAtomic(c.blobPath(hash), resp.Body, 0644); err != nil {
+			return err
+		}
+	}
+
+	entry := CacheEntry{
+		Request: CacheRequest{Method: req.Method, URL: req.URL},
+		Response: CacheResponse{
+			Status:     resp.Status,
+			StatusText: resp.StatusText,
+			Content:    CacheContent{Size: len(resp.Body), Hash: hash},
+		},
+		Recorded: time.Now(),
+	}
+	for name, value := range resp.Headers {
+		if isTransferHeader(name) {
+			continue
+		}
+		entry.Response.Headers = append(entry.Response.Headers, CacheHeader{Name: name, Value: value})
+		if strings.EqualFold(name, "Content-Type") {
+			entry.Response.Content.MimeType = value
+		}
+	}
+
+	c.mu.Lock()
+	c.Entries[cacheKey(req)] = entry
+	c.dirty = true
+	c.mu.Unlock()
+	return nil
+}
+
+func isTransferHeader(name string) bool {
+	for _, header := range transferHeaders {
+		if strings.EqualFold(name, header) {
+			return true
+		}
+	}
+	return false
+}
+
+// Save writes the index if anything was recorded.
+func (c *NetworkCache) Save() error {
+	c.mu.Lock()
+	if !c.dirty {
+		c.mu.Unlock()
+		return nil
+	}
+	data, err := json.MarshalIndent(c, "", "  ")
+	c.dirty = false
+	c.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	return common.WriteFileAtomic(filepath.Join(c.dir, networkCacheIndex), data, 0644)
+}
+
+// cacheable reports whether a response is recorded. Redirects are followed by the browser
+// before the response is intercepted, server errors are usually temporary.
+func cacheable(status int) bool {
+	return status >= http.StatusOK && status < http.StatusMultipleChoices ||
+		status >= http.StatusBadRequest && status < http.StatusInternalServerError
+}
@@ -0,0 +1,93 @@
+package cardconjurer
+
+import (
+	"github.com/chromedp/cdproto/network"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestNetworkCacheRecordAndReplay(t *testing.T) {
+	dir := t.TempDir()
+	frame := []byte("\x89PNG\r\n\x1a\nframe")
+	requests := []*network.Request{
+		{Method: http.MethodGet, URL: "https://cardconjurer.app/img/frames/m15/white.png"},
+		{Method: http.MethodGet, URL: "https://cardconjurer.app/img/frames/m15/white.png?v=2#top"},
+		{Method: http.MethodGet, URL: "https://cardconjurer.app/js/creator-23.js"},
+	}
+	bodies := [][]byte{frame, frame, []byte("var cardCanvas;")}
+
+	recorder, err := LoadNetworkCache(dir, NetworkCacheRecord)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp := recorder.serve(requests[0]); resp != nil {
+		t.Fatal("record mode must let requests through to the network")
+	}
+	for i, req := range requests {
+		err := recorder.record(req, &fetchResponse{
+			Status:  http.StatusOK,
+			Headers: map[string]string{"Content-Type": "image/png", "Content-Encoding": "gzip", "Content-Length": "12"},
+			Body:    bodies[i],
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := recorder.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	blobs, err := os.ReadDir(filepath.Join(dir, "blobs"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(blobs) != 2 {
+		t.Errorf("expected the frame to be stored once, got %d blobs", len(blobs))
+	}
+
+	replayer, err := LoadNetworkCache(dir, NetworkCacheReplay)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if replayer.Len() != len(requests) {
+		t.Fatalf("replaying %d requests, expected %d", replayer.Len(), len(requests))
+	}
+	for i, req := range requests {
+		resp := replayer.serve(req)
+		if resp == nil || resp.Error != "" {
+			t.Fatalf("%s was not replayed: %+v", req.URL, resp)
+		}
+		if resp.Status != http.StatusOK || string(resp.Body) != string(bodies[i]) {
+			t.Errorf("%s replayed %d %q", req.URL, resp.Status, resp.Body)
+		}
+		if resp.Headers["Content-Type"] != "image/png" || resp.Headers["Content-Encoding"] != "" || resp.Headers["Content-Length"] != "" {
+			t.Errorf("%s replayed headers %v", req.URL, resp.Headers)
+		}
+	}
+
+	// The fragment is not sent to the server
+	if resp := replayer.serve(&network.Request{Method: http.MethodGet, URL: "https://cardconjurer.app/js/creator-23.js#main"}); resp == nil || resp.Error != "" {
+		t.Error("url fragment is part of the cache key")
+	}
+	missing := replayer.serve(&network.Request{Method: http.MethodGet, URL: "https://cardconjurer.app/js/creator-24.js"})
+	if missing == nil || missing.Error != network.ErrorReasonInternetDisconnected {
+		t.Errorf("request that was not recorded returned %+v, expected it to fail", missing)
+	}
+	post := replayer.serve(&network.Request{Method: http.MethodPost, URL: "https://cardconjurer.app/js/creator-23.js"})
+	if post == nil || post.Error == "" {
+		t.Error("a POST was answered with the recorded GET")
+	}
+}
+
+func TestParseNetworkCacheMode(t *testing.T) {
+	for s, want := range map[string]NetworkCacheMode{"": NetworkCachePassthrough, "Record": NetworkCacheRecord, " replay ": NetworkCacheReplay} {
+		if got, err := ParseNetworkCacheMode(s); err != nil || got != want {
+			t.Errorf("ParseNetworkCacheMode(%q) = %q, %v, expected %q", s, got, err, want)
+		}
+	}
+	if _, err := ParseNetworkCacheMode("offline"); err == nil {
+		t.Error("expected an error for an unknown mode")
+	}
+}
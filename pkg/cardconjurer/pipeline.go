@@ -0,0 +1,139 @@
+package cardconjurer
+
+import (
+	"cardconjurer-automation/pkg/common"
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Names of the built-in pipeline steps
+const (
+	StepImport          = "import"
+	StepMargin          = "margin"
+	StepArtwork         = "artwork"
+	StepRemoveSetSymbol = "remove-set-symbol"
+	StepSave            = "save"
+)
+
+// DefaultPipeline is used if Config.Pipeline is empty.
+var DefaultPipeline = []string{StepImport, StepMargin, StepArtwork, StepRemoveSetSymbol, StepSave}
+
+// step is a named stage of the card pipeline. run returns an optional output,
+// e.g. the artwork file that was uploaded or the path of the saved card.
+type step struct {
+	name string
+	run  func(w *worker, state *cardState) (string, error)
+}
+
+type stepResult struct {
+	step     string
+	output   string
+	duration time.Duration
+}
+
+// cardState is passed through the pipeline, so steps can see the card and the
+// results of the steps that ran before them.
+type cardState struct {
+	card       common.CardInfo
+	browserCtx context.Context
+	results    []stepResult
+	// option is the Card Conjurer printing selected by the import step
+	option selectOption
+}
+
+func (s *cardState) result(stepName string) (stepResult, bool) {
+	for _, r := range s.results {
+		if r.step == stepName {
+			return r, true
+		}
+	}
+	return stepResult{}, false
+}
+
+var builtinSteps = map[string]step{
+	StepImport: {
+		name: StepImport,
+		run: func(w *worker, state *cardState) (string, error) {
+			option, err := w.importCard(state.card, state.browserCtx)
+			state.option = option
+			return option.Text, err
+		},
+	},
+	StepMargin: {
+		name: StepMargin,
+		run: func(w *worker, state *cardState) (string, error) {
+			return "", w.addMargin(state.browserCtx)
+		},
+	},
+	StepArtwork: {
+		name: StepArtwork,
+		run: func(w *worker, state *cardState) (string, error) {
+			return w.replaceArtwork(state.card, state.browserCtx)
+		},
+	},
+	StepRemoveSetSymbol: {
+		name: StepRemoveSetSymbol,
+		run: func(w *worker, state *cardState) (string, error) {
+			return "", w.removeSetSymbol(state.browserCtx)
+		},
+	},
+	StepSave: {
+		name: StepSave,
+		run: func(w *worker, state *cardState) (string, error) {
+			if _, ok := state.result(StepImport); !ok {
+				w.logger.Warn("Saving card without running the import step, the default card will be rendered")
+			}
+			return w.outputPath(state.card), w.saveCard(state.card, state.browserCtx)
+		},
+	},
+}
+
+// StepError is returned if a pipeline step fails.
+type StepError struct {
+	Step string
+	Err  error
+}
+
+func (e *StepError) Error() string {
+	return fmt.Sprintf("step %s: %v", e.Step, e.Err)
+}
+
+func (e *StepError) Unwrap() error {
+	return e.Err
+}
+
+// ParsePipeline parses a comma separated list of step names.
+func ParsePipeline(s string) []string {
+	var names []string
+	for _, name := range strings.Split(s, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// buildPipeline looks up the steps by name, an empty list returns the default pipeline.
+func buildPipeline(names []string) ([]step, error) {
+	if len(names) == 0 {
+		names = DefaultPipeline
+	}
+
+	seen := make(map[string]bool, len(names))
+	pipeline := make([]step, 0, len(names))
+	for _, name := range names {
+		s, ok := builtinSteps[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown pipeline step %q, available steps: %s", name, strings.Join(DefaultPipeline, ", "))
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("pipeline step %q is listed more than once", name)
+		}
+		seen[name] = true
+		pipeline = append(pipeline, s)
+	}
+
+	return pipeline, nil
+}
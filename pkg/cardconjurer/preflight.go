@@ -0,0 +1,216 @@
+package cardconjurer
+
+import (
+	"cardconjurer-automation/pkg/common"
+	"context"
+	"errors"
+	"fmt"
+	"go.uber.org/zap"
+	"net/url"
+	"slices"
+	"strings"
+)
+
+// versionScript looks for a versioned creator script like /js/creator-23.js, Card Conjurer
+// bumps the number whenever the creator changes.
+const versionScript = `(() => {
+	for (const s of document.querySelectorAll('script[src]')) {
+		const m = s.getAttribute('src').match(/creator-([\w.]+)\.js/);
+		if (m) {
+			return m[1];
+		}
+	}
+	return '';
+})()`
+
+// SiteCheck is the outcome of checking a Card Conjurer site.
+type SiteCheck struct {
+	BaseUrl string
+	// Version of the creator script, empty if it could not be detected
+	Version   string
+	Selectors SelectorChecks
+
+	// frames are the options of the 'autoFrame' dropdown
+	frames []selectOption
+}
+
+// PreflightError is returned by Run if the site does not provide the UI the selector profile expects.
+type PreflightError struct {
+	BaseUrl string
+	Version string
+	Missing SelectorChecks
+}
+
+func (e *PreflightError) Error() string {
+	names := make([]string, 0, len(e.Missing))
+	for _, check := range e.Missing {
+		names = append(names, fmt.Sprintf("%s (%s)", check.Name, check.Selector))
+	}
+	version := e.Version
+	if version == "" {
+		version = "unknown"
+	}
+	return fmt.Sprintf("Card Conjurer at %s (creator version %s) is missing %d required element(s): %s. "+
+		"Check --base-url or provide a matching selector profile with --selectors",
+		e.BaseUrl, version, len(e.Missing), strings.Join(names, ", "))
+}
+
+// validateBaseUrl checks that the base url is an absolute http(s) or file url.
+func validateBaseUrl(baseUrl string) error {
+	if baseUrl == "" {
+		return errors.New("base url is empty, set it with --base-url or serve a local checkout with --site-dir")
+	}
+	u, err := url.Parse(baseUrl)
+	if err != nil {
+		return fmt.Errorf("invalid base url %q: %v", baseUrl, err)
+	}
+	switch u.Scheme {
+	case "http", "https":
+		if u.Host == "" {
+			return fmt.Errorf("base url %q has no host", baseUrl)
+		}
+	case "file":
+	default:
+		return fmt.Errorf("base url %q must start with http://, https:// or file://", baseUrl)
+	}
+	return nil
+}
+
+// CheckSite opens Config.BaseUrl in a single browser, detects the Card Conjurer version and
+// verifies that every entry of the selector profile resolves on the page.
+func CheckSite(ctx context.Context, cfg *Config, logger *zap.SugaredLogger) (*SiteCheck, error) {
+	return checkSite(ctx, newWorker(0, logger, cfg, nil))
+}
+
+// checkSite runs the check in the browser of the worker.
+func checkSite(ctx context.Context, w *worker) (*SiteCheck, error) {
+	cfg := w.config
+	if err := validateBaseUrl(cfg.BaseUrl); err != nil {
+		return nil, err
+	}
+
+	browserCtx, err := w.openBrowser(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error opening %s: %v", cfg.BaseUrl, err)
+	}
+	defer w.closeBrowser(browserCtx)
+
+	check := &SiteCheck{BaseUrl: cfg.BaseUrl}
+	if err := w.driver.Evaluate(browserCtx, versionScript, &check.Version); err != nil {
+		w.logger.Warnw("Could not detect Card Conjurer version", "error", err)
+	}
+
+	check.Selectors, err = w.checkSelectors(browserCtx)
+	if err != nil {
+		return nil, err
+	}
+	if check.frames, err = w.frameOptions(browserCtx); err != nil {
+		w.logger.Warnw("Could not read the frames Card Conjurer offers", "error", err)
+	}
+	return check, nil
+}
+
+// preflight checks the site once before any worker is started, so a wrong base url,
+// a changed UI or an unknown frame of the cards fails with a single error instead of
+// in every worker.
+func (cc *CardConjurer) preflight(ctx context.Context, cards []common.CardInfo) error {
+	cc.logger.Infof("Running preflight check of %s", cc.config.BaseUrl)
+	check, err := checkSite(ctx, cc.newWorker(0, cc.logger.With("phase", "preflight"), nil))
+	if err != nil {
+		return fmt.Errorf("preflight check failed: %v", err)
+	}
+	return cc.verifySite(check, cards)
+}
+
+// verifySite fails if the checked site lacks an element or a frame the run depends on.
+func (cc *CardConjurer) verifySite(check *SiteCheck, cards []common.CardInfo) error {
+	// Elements the configured pipeline and capture mode never use don't stop the run
+	needed := cc.neededSelectors()
+	var missing SelectorChecks
+	for _, m := range check.Selectors.Missing() {
+		if needed[m.Name] {
+			missing = append(missing, m)
+		} else if m.Name == "card_canvas_variable" && cc.config.captureMode() == CaptureCanvas {
+			cc.logger.Warnw("Card canvas missing on the page, cards are downloaded instead", "name", m.Name, "selector", m.Selector)
+		} else {
+			cc.logger.Warnw("Unused element missing on the page", "name", m.Name, "selector", m.Selector)
+		}
+	}
+	if len(missing) > 0 {
+		return &PreflightError{BaseUrl: check.BaseUrl, Version: check.Version, Missing: missing}
+	}
+	if err := cc.checkFrames(check.frames, cards); err != nil {
+		return fmt.Errorf("preflight check failed: %v", err)
+	}
+
+	if check.Version != "" {
+		cc.logger.Infof("Preflight passed, Card Conjurer creator version %s", check.Version)
+	} else {
+		cc.logger.Info("Preflight passed, Card Conjurer version unknown")
+	}
+	return nil
+}
+
+// stepSelectors are the selector checks every pipeline step depends on.
+var stepSelectors = map[string][]string{
+	StepImport:          {"tabs.import", "import_name", "import_index", "import_all_prints", "auto_frame"},
+	StepMargin:          {"tabs.frame", "frame_group", "margin_frame_group", "add_to_full", "margin_thumbnail"},
+	StepArtwork:         {"tabs.art", "art_upload"},
+	StepRemoveSetSymbol: {"tabs.set_symbol", "remove_set_symbol"},
+}
+
+// neededSelectors returns the names of the selector checks the run depends on. The preview
+// canvas is only watched by the margin step and is never required. The card canvas is only
+// required if there is no download to fall back to.
+func (cc *CardConjurer) neededSelectors() map[string]bool {
+	needed := make(map[string]bool)
+	for _, st := range cc.pipeline {
+		for _, name := range stepSelectors[st.name] {
+			needed[name] = true
+		}
+		if st.name != StepSave {
+			continue
+		}
+		if cc.capturesWithoutFallback() {
+			needed["card_canvas_variable"] = true
+		} else {
+			needed["download"] = true
+		}
+	}
+	return needed
+}
+
+// capturesWithoutFallback reports whether cards are only captured from the canvas. Remote
+// browsers don't support downloads, so saveCard has nothing to fall back to.
+func (cc *CardConjurer) capturesWithoutFallback() bool {
+	return cc.config.captureMode() == CaptureCanvas && len(cc.config.Browser.RemoteURLs) > 0
+}
+
+// checkFrames verifies that Card Conjurer offers the frame of every card that is imported.
+func (cc *CardConjurer) checkFrames(frames []selectOption, cards []common.CardInfo) error {
+	if len(frames) == 0 || !slices.ContainsFunc(cc.pipeline, func(s step) bool { return s.name == StepImport }) {
+		return nil
+	}
+
+	var unknown []string
+	usedBy := make(map[string][]string)
+	for _, card := range cards {
+		frame := cc.config.frameFor(card)
+		if _, err := matchFrame(frame, frames); err == nil {
+			continue
+		}
+		if _, ok := usedBy[frame]; !ok {
+			unknown = append(unknown, frame)
+		}
+		usedBy[frame] = append(usedBy[frame], card.GetFullName())
+	}
+	if len(unknown) == 0 {
+		return nil
+	}
+
+	problems := make([]string, 0, len(unknown))
+	for _, frame := range unknown {
+		problems = append(problems, fmt.Sprintf("%q (%s)", frame, strings.Join(usedBy[frame], ", ")))
+	}
+	return fmt.Errorf("Card Conjurer does not offer the frame(s) %s, available frames: %s", strings.Join(problems, ", "), frameList(frames))
+}
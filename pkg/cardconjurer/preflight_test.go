@@ -0,0 +1,141 @@
+package cardconjurer
+
+import (
+	"cardconjurer-automation/pkg/common"
+	"cardconjurer-automation/pkg/decklist_parser"
+	"context"
+	"fmt"
+	"go.uber.org/zap/zaptest"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestNeededSelectors(t *testing.T) {
+	tests := []struct {
+		name     string
+		pipeline []string
+		capture  string
+		remote   []string
+		want     []string
+	}{
+		{
+			name: "default pipeline",
+			want: []string{"add_to_full", "art_upload", "auto_frame", "download", "frame_group", "import_all_prints", "import_index",
+				"import_name", "margin_frame_group", "margin_thumbnail", "remove_set_symbol", "tabs.art", "tabs.frame", "tabs.import", "tabs.set_symbol"},
+		},
+		{
+			name:     "canvas with remote browser",
+			pipeline: []string{StepImport, StepSave},
+			capture:  CaptureCanvas,
+			remote:   []string{"ws://127.0.0.1:9222"},
+			want:     []string{"auto_frame", "card_canvas_variable", "import_all_prints", "import_index", "import_name", "tabs.import"},
+		},
+		{
+			name:     "download without margin",
+			pipeline: []string{StepImport, StepSave},
+			capture:  CaptureDownload,
+			want:     []string{"auto_frame", "download", "import_all_prints", "import_index", "import_name", "tabs.import"},
+		},
+		{
+			name:     "no save",
+			pipeline: []string{StepArtwork},
+			want:     []string{"art_upload", "tabs.art"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pipeline, err := buildPipeline(tt.pipeline)
+			if err != nil {
+				t.Fatal(err)
+			}
+			cc := &CardConjurer{config: &Config{CaptureMode: tt.capture, Browser: BrowserConfig{RemoteURLs: tt.remote}}, pipeline: pipeline}
+			if got := slices.Sorted(maps.Keys(cc.neededSelectors())); !slices.Equal(got, tt.want) {
+				t.Errorf("needed %q, expected %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRunSkipsPreflightWhenUpToDate(t *testing.T) {
+	dir := t.TempDir()
+	card := &decklist_parser.Card{Count: 1, Name: "Sol Ring", Set: "C21", CollectorNumber: "263"}
+	cfg := &Config{
+		Workers:           1,
+		ProjectName:       "test",
+		OutputCardsFolder: dir,
+		ManifestPath:      filepath.Join(dir, "manifest.json"),
+	}
+	cc, err := New(cfg, zaptest.NewLogger(t).Sugar(), []common.CardInfo{card})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	output := cfg.outputPath(card)
+	if err := os.WriteFile(output, pngSignature, 0644); err != nil {
+		t.Fatal(err)
+	}
+	inputs, err := cc.cardInputs(card)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cc.manifest.set(card.GetPrintingID(), ManifestEntry{ManifestInputs: inputs, OutputPath: output})
+
+	// Without a base url a preflight check would fail
+	if err := cc.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if report := cc.Report(); report.Skipped != 1 {
+		t.Errorf("expected the card to be skipped, got %+v", report.Cards)
+	}
+	if got := <-cc.GetOutputChan(); got != card {
+		t.Errorf("expected %s in the output, got %v", card.GetFullName(), got)
+	}
+}
+
+func TestCheckFrames(t *testing.T) {
+	frames := []selectOption{{Value: "Seventh", Text: "7th Edition"}, {Value: "M15", Text: "M15"}}
+	solRing := &decklist_parser.Card{Count: 1, Name: "Sol Ring", Set: "C21", CollectorNumber: "263"}
+	island := &decklist_parser.Card{Count: 1, Name: "Island", Set: "UNF", CollectorNumber: "235", Extra: map[string]string{"frame": "Borderless"}}
+	plains := &decklist_parser.Card{Count: 1, Name: "Plains", Set: "UNF", CollectorNumber: "234", Extra: map[string]string{"frame": "m15"}}
+
+	tests := []struct {
+		name     string
+		frame    string
+		pipeline []string
+		frames   []selectOption
+		cards    []common.CardInfo
+		want     string
+	}{
+		{name: "known frames", frame: "7th edition", cards: []common.CardInfo{solRing, plains}},
+		{
+			name:  "unknown frames",
+			frame: "Eighth",
+			cards: []common.CardInfo{solRing, island, plains},
+			want: `Card Conjurer does not offer the frame(s) "Eighth" (Sol Ring (C21 #263)), "Borderless" (Island (UNF #235)), ` +
+				"available frames: Seventh (7th Edition), M15 (M15)",
+		},
+		{name: "no import step", frame: "Eighth", pipeline: []string{StepArtwork, StepSave}, cards: []common.CardInfo{solRing}},
+		{name: "frames unknown", frame: "Eighth", frames: []selectOption{}, cards: []common.CardInfo{solRing}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pipeline, err := buildPipeline(tt.pipeline)
+			if err != nil {
+				t.Fatal(err)
+			}
+			cc := &CardConjurer{config: &Config{Frame: tt.frame}, pipeline: pipeline}
+			available := frames
+			if tt.frames != nil {
+				available = tt.frames
+			}
+
+			err = cc.checkFrames(available, tt.cards)
+			if got := fmt.Sprint(err); (err != nil || tt.want != "") && got != tt.want {
+				t.Errorf("error %q, expected %q", got, tt.want)
+			}
+		})
+	}
+}
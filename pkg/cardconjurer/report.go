@@ -0,0 +1,127 @@
+package cardconjurer
+
+import (
+	"cardconjurer-automation/pkg/common"
+	"encoding/json"
+	"fmt"
+	"io"
+	"text/tabwriter"
+	"time"
+)
+
+type CardStatus string
+
+const (
+	StatusSucceeded CardStatus = "succeeded"
+	StatusFailed    CardStatus = "failed"
+	StatusSkipped   CardStatus = "skipped"
+)
+
+// CardReport is the final outcome of a single deck entry.
+type CardReport struct {
+	Name            string     `json:"name"`
+	Set             string     `json:"set,omitempty"`
+	CollectorNumber string     `json:"collector_number,omitempty"`
+	Count           int        `json:"count"`
+	Status          CardStatus `json:"status"`
+	// FailedStep is the pipeline step that failed last, "browser" if no browser could be opened
+	FailedStep string `json:"failed_step,omitempty"`
+	Error      string `json:"error,omitempty"`
+	// Reason explains why a card was skipped
+	Reason string `json:"reason,omitempty"`
+	// Resolved is the Card Conjurer printing that was rendered, Fallback the policy that picked it
+	// if the printing of the decklist was not available
+	Resolved   string `json:"resolved,omitempty"`
+	Fallback   string `json:"fallback,omitempty"`
+	Attempts   int    `json:"attempts"`
+	DurationMs int64  `json:"duration_ms"`
+	OutputPath string `json:"output_path,omitempty"`
+	// WorkerID is -1 for cards no worker picked up
+	WorkerID int `json:"worker_id"`
+
+	card   common.CardInfo
+	option selectOption
+}
+
+func newCardReport(card common.CardInfo, workerID int) CardReport {
+	return CardReport{
+		Name:            card.GetName(),
+		Set:             card.GetSet(),
+		CollectorNumber: card.GetCollectorNumber(),
+		Count:           card.GetCount(),
+		WorkerID:        workerID,
+		card:            card,
+	}
+}
+
+func (c CardReport) displayName() string {
+	if c.card != nil {
+		return c.card.GetFullName()
+	}
+	return c.Name
+}
+
+// Report summarizes a run of CardConjurer.Run.
+type Report struct {
+	Project   string       `json:"project"`
+	Started   time.Time    `json:"started"`
+	Finished  time.Time    `json:"finished"`
+	Succeeded int          `json:"succeeded"`
+	Failed    int          `json:"failed"`
+	Skipped   int          `json:"skipped"`
+	Cards     []CardReport `json:"cards"`
+}
+
+func (r *Report) add(card CardReport) {
+	r.Cards = append(r.Cards, card)
+	switch card.Status {
+	case StatusSucceeded:
+		r.Succeeded++
+	case StatusFailed:
+		r.Failed++
+	case StatusSkipped:
+		r.Skipped++
+	}
+}
+
+// Save writes the report as JSON.
+func (r *Report) Save(path string) error {
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	return common.WriteFileAtomic(path, data, 0644)
+}
+
+// WriteTable prints a human readable summary of the report.
+func (r *Report) WriteTable(out io.Writer) error {
+	tw := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "STATUS\tCARD\tATTEMPTS\tDURATION\tWORKER\tSTEP\tDETAILS")
+	for _, c := range r.Cards {
+		worker := "-"
+		if c.WorkerID >= 0 {
+			worker = fmt.Sprint(c.WorkerID)
+		}
+		details := c.Error
+		if details == "" {
+			details = c.Reason
+		}
+		duration := (time.Duration(c.DurationMs) * time.Millisecond).Round(100 * time.Millisecond)
+		fmt.Fprintf(tw, "%s\t%s\t%d\t%s\t%s\t%s\t%s\n", c.Status, c.displayName(), c.Attempts, duration, worker, c.FailedStep, truncate(details, 80))
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	_, err := fmt.Fprintf(out, "%d succeeded, %d failed, %d skipped in %s\n",
+		r.Succeeded, r.Failed, r.Skipped, r.Finished.Sub(r.Started).Round(time.Second))
+	return err
+}
+
+func truncate(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	return string(runes[:n-1]) + "…"
+}
@@ -0,0 +1,281 @@
+package cardconjurer
+
+import (
+	"cardconjurer-automation/pkg/common"
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+)
+
+// FallbackPolicy decides what is rendered if Card Conjurer does not offer the printing of a card.
+type FallbackPolicy string
+
+const (
+	// FallbackFail fails the card (default)
+	FallbackFail FallbackPolicy = "fail"
+	// FallbackAny uses the first printing Card Conjurer lists for the card name
+	FallbackAny FallbackPolicy = "any"
+	// FallbackNewest uses the most recently released printing
+	FallbackNewest FallbackPolicy = "newest"
+	// FallbackPreferred uses a printing of the first set of Config.PreferredSets that has one
+	FallbackPreferred FallbackPolicy = "preferred"
+)
+
+// searchTimeout is how long a Card Conjurer search may take before it counts as empty
+const searchTimeout = 20 * time.Second
+
+// maxCandidates limits the closest printings listed for unresolved cards
+const maxCandidates = 5
+
+// optionPattern splits an option of the import dropdown like "Sol Ring (C21 #263)"
+var optionPattern = regexp.MustCompile(`^(.+) \(([^()#\s]+) #([^()]+)\)$`)
+
+func ParseFallbackPolicy(s string) (FallbackPolicy, error) {
+	switch p := FallbackPolicy(strings.ToLower(strings.TrimSpace(s))); p {
+	case "":
+		return FallbackFail, nil
+	case FallbackFail, FallbackAny, FallbackNewest, FallbackPreferred:
+		return p, nil
+	default:
+		return "", fmt.Errorf("unknown fallback policy %q, expected %q, %q, %q or %q", s, FallbackFail, FallbackAny, FallbackNewest, FallbackPreferred)
+	}
+}
+
+// resolution is the Card Conjurer printing a card is rendered from.
+type resolution struct {
+	Text  string
+	Value string
+	// Fallback is the policy that picked the printing, empty for exact matches
+	Fallback FallbackPolicy
+	// Pinned is set if the printing comes from the lock file
+	Pinned bool
+}
+
+// ResolveError is returned if Card Conjurer offers no printing for a card or several
+// printings match it equally well.
+type ResolveError struct {
+	Card       string
+	Candidates []string
+	Ambiguous  bool
+}
+
+func (e *ResolveError) Error() string {
+	if e.Ambiguous {
+		return fmt.Sprintf("%q matches several Card Conjurer printings equally well: %s", e.Card, strings.Join(e.Candidates, "; "))
+	}
+	if len(e.Candidates) == 0 {
+		return fmt.Sprintf("Card Conjurer found no printing of %q", e.Card)
+	}
+	return fmt.Sprintf("no Card Conjurer printing matches %q, closest: %s", e.Card, strings.Join(e.Candidates, "; "))
+}
+
+// resolveOption picks the search result the card is rendered from. The printing has to match
+// according to the matcher, unless the fallback policy of the config allows another one.
+func resolveOption(card common.CardInfo, options []selectOption, cfg *Config) (resolution, error) {
+	want := card.GetFullName()
+	option, ambiguous, ok := matchOption(card, options, cfg.matchThreshold())
+	if ok {
+		return resolution{Text: option.Text, Value: option.Value}, nil
+	}
+	if len(ambiguous) > 0 {
+		return resolution{}, &ResolveError{Card: want, Candidates: ambiguous, Ambiguous: true}
+	}
+
+	var sameName []selectOption
+	for _, option := range options {
+		if name, _, _, ok := parseOption(option.Text); ok && normalizeName(name) == normalizeName(card.GetName()) {
+			sameName = append(sameName, option)
+		}
+	}
+	if len(sameName) == 0 {
+		return resolution{}, &ResolveError{Card: want, Candidates: closestOptions(card, options, maxCandidates)}
+	}
+
+	switch policy := cfg.Fallback; policy {
+	case FallbackAny:
+		return resolution{Text: sameName[0].Text, Value: sameName[0].Value, Fallback: policy}, nil
+	case FallbackNewest:
+		// Without release dates the search order is used, Scryfall lists newer printings first
+		newest := sameName[0]
+		for _, option := range sameName[1:] {
+			if option.Released > newest.Released {
+				newest = option
+			}
+		}
+		return resolution{Text: newest.Text, Value: newest.Value, Fallback: policy}, nil
+	case FallbackPreferred:
+		for _, set := range cfg.PreferredSets {
+			for _, option := range sameName {
+				if _, optionSet, _, _ := parseOption(option.Text); strings.EqualFold(optionSet, strings.TrimSpace(set)) {
+					return resolution{Text: option.Text, Value: option.Value, Fallback: policy}, nil
+				}
+			}
+		}
+	}
+	return resolution{}, &ResolveError{Card: want, Candidates: closestOptions(card, options, maxCandidates)}
+}
+
+// findOption returns the option with the given text, ignoring surrounding whitespace.
+func findOption(options []selectOption, text string) (selectOption, bool) {
+	for _, option := range options {
+		if option.Text == text {
+			return option, true
+		}
+	}
+	for _, option := range options {
+		if strings.TrimSpace(option.Text) == strings.TrimSpace(text) {
+			return option, true
+		}
+	}
+	return selectOption{}, false
+}
+
+// parseOption splits the text of a search result into name, set and collector number.
+func parseOption(text string) (name, set, collectorNumber string, ok bool) {
+	m := optionPattern.FindStringSubmatch(strings.TrimSpace(text))
+	if m == nil {
+		return "", "", "", false
+	}
+	return m[1], m[2], m[3], true
+}
+
+func levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(rb)]
+}
+
+// resolveCards searches every card in Card Conjurer before anything is rendered, spread over
+// the browsers of the workers. Their browsers stay open, so rendering doesn't start them again.
+// With usePins cards pinned in the lock file are not searched. Cards that can't be resolved
+// are reported as failed, the resolved ones are returned in their original order.
+func (cc *CardConjurer) resolveCards(ctx, browserParent context.Context, workers []*worker, cards []common.CardInfo, usePins bool) []common.CardInfo {
+	search := cards
+	if usePins && cc.lock != nil {
+		search = cc.pinnedResolutions(cards)
+	}
+	cc.logger.Infof("Resolving %d card(s) in Card Conjurer", len(search))
+
+	var mu sync.Mutex
+	unresolved := make(map[common.CardInfo]CardReport)
+	jobs := make(chan common.CardInfo)
+
+	var wg sync.WaitGroup
+	for _, w := range workers[:min(len(workers), len(search))] {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for card := range jobs {
+				res, attempts, err := w.resolveCard(ctx, browserParent, card)
+				mu.Lock()
+				if err == nil {
+					cc.resolutions[card] = res
+				} else if ctx.Err() == nil {
+					result := newCardReport(card, w.workerID)
+					result.Status = StatusFailed
+					result.FailedStep = "resolve"
+					result.Error = err.Error()
+					result.Attempts = attempts
+					unresolved[card] = result
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+
+send:
+	for _, card := range search {
+		select {
+		case <-ctx.Done():
+			break send
+		case jobs <- card:
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	if len(unresolved) > 0 {
+		var sb strings.Builder
+		tw := tabwriter.NewWriter(&sb, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "CARD\tPROBLEM")
+		for _, card := range cards {
+			if result, ok := unresolved[card]; ok {
+				fmt.Fprintf(tw, "%s\t%s\n", result.displayName(), result.Error)
+				cc.addResult(result)
+			}
+		}
+		tw.Flush()
+		cc.logger.Warnf("%d card(s) could not be resolved and will not be rendered:\n%s", len(unresolved), sb.String())
+	}
+
+	var resolved []common.CardInfo
+	for _, card := range cards {
+		if res, ok := cc.resolutions[card]; ok {
+			if res.Fallback != "" && !res.Pinned {
+				cc.logger.Warnf("Card '%s' is not offered by Card Conjurer, rendering '%s' (fallback %s)", card.GetFullName(), res.Text, res.Fallback)
+			}
+			resolved = append(resolved, card)
+		}
+	}
+	return resolved
+}
+
+// resolveCard searches the card and picks its printing. Search errors are retried according
+// to the retry policy in a new browser session, a missing printing is not retried.
+func (w *worker) resolveCard(ctx, browserParent context.Context, card common.CardInfo) (resolution, int, error) {
+	policy := w.config.Retry.withDefaults()
+	sel := w.config.selectors()
+
+	var err error
+	attempts := 0
+	for attempts < policy.MaxAttempts {
+		if attempts > 0 {
+			if !sleep(ctx, policy.backoff(attempts)) {
+				return resolution{}, attempts, ctx.Err()
+			}
+			w.restartBrowser()
+		}
+		attempts++
+
+		if w.browserCtx == nil {
+			w.browserCtx, err = w.openBrowser(browserParent)
+			if err != nil {
+				w.browserCtx = nil
+				continue
+			}
+			if err = w.openTab(w.browserCtx, sel.Tabs.Import, sel.ImportName); err != nil {
+				continue
+			}
+		}
+
+		var options []selectOption
+		options, err = w.searchCard(w.browserCtx, card)
+		if err != nil {
+			w.logger.Warnw("Error searching card", "card", card.GetFullName(), "attempt", attempts, "error", err)
+			continue
+		}
+
+		res, err := resolveOption(card, options, w.config)
+		return res, attempts, err
+	}
+	return resolution{}, attempts, err
+}
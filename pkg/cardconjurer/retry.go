@@ -0,0 +1,84 @@
+package cardconjurer
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// RetryPolicy decides how often and when a failed card is rendered again.
+type RetryPolicy struct {
+	// MaxAttempts is the number of attempts per card, including the first one
+	MaxAttempts int
+	// InitialBackoff is the wait before the first retry, it doubles with every further retry
+	InitialBackoff time.Duration
+	// MaxBackoff caps the wait between retries, 0 uses the cap of DefaultRetryPolicy
+	MaxBackoff time.Duration
+	// FreshBrowserAfter opens a new browser session for the next attempt once a card
+	// failed this many times, 0 disables it
+	FreshBrowserAfter int
+	// RestartAfterFailures restarts the worker's browser after this many consecutive
+	// failed attempts across all cards, 0 disables it
+	RestartAfterFailures int
+}
+
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts:          3,
+	InitialBackoff:       2 * time.Second,
+	MaxBackoff:           30 * time.Second,
+	FreshBrowserAfter:    2,
+	RestartAfterFailures: 3,
+}
+
+// withDefaults returns DefaultRetryPolicy for an unset policy. Otherwise only MaxBackoff
+// is filled in if it is not set, so an InitialBackoff of 0 retries right away.
+func (p RetryPolicy) withDefaults() RetryPolicy {
+	if p == (RetryPolicy{}) {
+		return DefaultRetryPolicy
+	}
+	if p.MaxBackoff == 0 {
+		p.MaxBackoff = max(DefaultRetryPolicy.MaxBackoff, p.InitialBackoff)
+	}
+	return p
+}
+
+// Validate checks a policy that is set, the zero value is valid and uses DefaultRetryPolicy.
+func (p RetryPolicy) Validate() error {
+	if p == (RetryPolicy{}) {
+		return nil
+	}
+	switch {
+	case p.MaxAttempts < 1:
+		return fmt.Errorf("max attempts must be at least 1, got %d", p.MaxAttempts)
+	case p.InitialBackoff < 0:
+		return fmt.Errorf("backoff must not be negative, got %s", p.InitialBackoff)
+	case p.MaxBackoff < 0:
+		return fmt.Errorf("max backoff must not be negative, got %s", p.MaxBackoff)
+	case p.FreshBrowserAfter < 0:
+		return fmt.Errorf("fresh browser after must not be negative, got %d", p.FreshBrowserAfter)
+	case p.RestartAfterFailures < 0:
+		return fmt.Errorf("restart after must not be negative, got %d", p.RestartAfterFailures)
+	}
+	return nil
+}
+
+// backoff returns the wait before the given retry (1 for the first retry).
+func (p RetryPolicy) backoff(retry int) time.Duration {
+	backoff := p.InitialBackoff
+	for i := 1; i < retry && backoff < p.MaxBackoff; i++ {
+		backoff *= 2
+	}
+	return min(backoff, p.MaxBackoff)
+}
+
+// sleep waits for the duration and returns false if the context is cancelled first.
+func sleep(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
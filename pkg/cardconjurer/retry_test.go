@@ -0,0 +1,68 @@
+package cardconjurer
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRetryPolicy(t *testing.T) {
+	tests := []struct {
+		name     string
+		policy   RetryPolicy
+		wantErr  bool
+		want     RetryPolicy
+		backoffs []time.Duration
+	}{
+		{
+			name:     "unset",
+			want:     DefaultRetryPolicy,
+			backoffs: []time.Duration{2 * time.Second, 4 * time.Second, 8 * time.Second, 16 * time.Second, 30 * time.Second},
+		},
+		{
+			name:     "no backoff",
+			policy:   RetryPolicy{MaxAttempts: 3},
+			want:     RetryPolicy{MaxAttempts: 3, MaxBackoff: DefaultRetryPolicy.MaxBackoff},
+			backoffs: []time.Duration{0, 0},
+		},
+		{
+			name:     "backoff above the default cap",
+			policy:   RetryPolicy{MaxAttempts: 2, InitialBackoff: time.Minute},
+			want:     RetryPolicy{MaxAttempts: 2, InitialBackoff: time.Minute, MaxBackoff: time.Minute},
+			backoffs: []time.Duration{time.Minute, time.Minute},
+		},
+		{
+			name:     "capped",
+			policy:   RetryPolicy{MaxAttempts: 5, InitialBackoff: time.Second, MaxBackoff: 3 * time.Second},
+			want:     RetryPolicy{MaxAttempts: 5, InitialBackoff: time.Second, MaxBackoff: 3 * time.Second},
+			backoffs: []time.Duration{time.Second, 2 * time.Second, 3 * time.Second},
+		},
+		{name: "no attempts", policy: RetryPolicy{InitialBackoff: time.Second}, wantErr: true},
+		{name: "negative attempts", policy: RetryPolicy{MaxAttempts: -1}, wantErr: true},
+		{name: "negative backoff", policy: RetryPolicy{MaxAttempts: 1, InitialBackoff: -time.Second}, wantErr: true},
+		{name: "negative restart", policy: RetryPolicy{MaxAttempts: 1, RestartAfterFailures: -1}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.policy.Validate()
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			policy := tt.policy.withDefaults()
+			if policy != tt.want {
+				t.Errorf("policy %+v, expected %+v", policy, tt.want)
+			}
+			for i, want := range tt.backoffs {
+				if got := policy.backoff(i + 1); got != want {
+					t.Errorf("backoff before retry %d is %s, expected %s", i+1, got, want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,407 @@
+package cardconjurer
+
+import (
+	"cmp"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/chromedp/cdproto/network"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// DefaultScryfallURLPattern matches the Scryfall API requests of Card Conjurer.
+const DefaultScryfallURLPattern = "https://api.scryfall.com/cards/*"
+
+// ScryfallData answers Scryfall API card requests from a local bulk data file, e.g. the
+// "Default Cards" export from https://scryfall.com/docs/api/bulk-data. Supported are
+// /cards/search, /cards/named and /cards/:set/:number.
+type ScryfallData struct {
+	cards []scryfallCard
+	// byName indexes the cards by normalized name and face names
+	byName map[string][]int
+}
+
+type scryfallCard struct {
+	Name            string `json:"name"`
+	Set             string `json:"set"`
+	CollectorNumber string `json:"collector_number"`
+	Lang            string `json:"lang"`
+	ReleasedAt      string `json:"released_at"`
+	OracleID        string `json:"oracle_id"`
+	IllustrationID  string `json:"illustration_id"`
+	CardFaces       []struct {
+		Name           string `json:"name"`
+		IllustrationID string `json:"illustration_id"`
+	} `json:"card_faces"`
+
+	raw json.RawMessage
+	// names are the normalized name and face names
+	names []string
+}
+
+func (c *scryfallCard) normalizeNames() {
+	c.names = []string{normalizeName(c.Name)}
+	for _, face := range c.CardFaces {
+		if name := normalizeName(face.Name); name != c.names[0] {
+			c.names = append(c.names, name)
+		}
+	}
+}
+
+func (c *scryfallCard) illustration() string {
+	if c.IllustrationID == "" && len(c.CardFaces) > 0 {
+		return c.CardFaces[0].IllustrationID
+	}
+	return c.IllustrationID
+}
+
+// LoadScryfallData reads a bulk data file, a JSON array of card objects.
+func LoadScryfallData(path string) (*ScryfallData, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	dec := json.NewDecoder(file)
+	if tok, err := dec.Token(); err != nil {
+		return nil, err
+	} else if tok != json.Delim('[') {
+		return nil, errors.New("bulk data is not a JSON array of cards")
+	}
+
+	d := &ScryfallData{byName: make(map[string][]int)}
+	for dec.More() {
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err != nil {
+			return nil, fmt.Errorf("error reading card %d: %v", len(d.cards)+1, err)
+		}
+		card := scryfallCard{raw: raw}
+		if err := json.Unmarshal(raw, &card); err != nil {
+			return nil, fmt.Errorf("error reading card %d: %v", len(d.cards)+1, err)
+		}
+		if card.Name == "" {
+			continue
+		}
+		card.normalizeNames()
+		for _, name := range card.names {
+			d.byName[name] = append(d.byName[name], len(d.cards))
+		}
+		d.cards = append(d.cards, card)
+	}
+	return d, nil
+}
+
+// Len returns the number of cards.
+func (d *ScryfallData) Len() int {
+	return len(d.cards)
+}
+
+// scryfallQuery is the part of Scryfall's search syntax a card is matched by: names,
+// set, collector number and language. Other filters are ignored.
+type scryfallQuery struct {
+	// exact names match the name or a face name
+	exact []string
+	// words must all be part of the name
+	words    []string
+	patterns []*regexp.Regexp
+	set      string
+	number   string
+	lang     string
+	unique   string
+	order    string
+	dir      string
+}
+
+// parseScryfallQuery parses the q parameter of a search, e.g. `!"Sol Ring" set:c21 cn:263`.
+func parseScryfallQuery(q string) (scryfallQuery, error) {
+	var query scryfallQuery
+	for _, token := range splitScryfallQuery(q) {
+		if strings.HasPrefix(token, "-") || token == "(" || token == ")" || strings.EqualFold(token, "or") {
+			continue
+		}
+		if name, ok := strings.CutPrefix(token, "!"); ok {
+			query.exact = append(query.exact, normalizeName(unquote(name)))
+			continue
+		}
+
+		i := strings.IndexAny(token, `:=<>"/`)
+		if i <= 0 || (token[i] != ':' && token[i] != '=') {
+			if i > 0 {
+				// Comparisons like "cmc>3"
+				continue
+			}
+			query.words = append(query.words, normalizeName(unquote(token)))
+			continue
+		}
+
+		key, value := strings.ToLower(token[:i]), token[i+1:]
+		switch key {
+		case "name":
+			if len(value) > 1 && strings.HasPrefix(value, "/") && strings.HasSuffix(value, "/") {
+				pattern, err := regexp.Compile("(?i)" + value[1:len(value)-1])
+				if err != nil {
+					return query, fmt.Errorf("invalid name pattern %s: %v", value, err)
+				}
+				query.patterns = append(query.patterns, pattern)
+			} else if token[i] == '=' {
+				query.exact = append(query.exact, normalizeName(unquote(value)))
+			} else {
+				query.words = append(query.words, normalizeName(unquote(value)))
+			}
+		case "set", "s", "e", "edition":
+			query.set = strings.ToLower(unquote(value))
+		case "cn", "number":
+			query.number = unquote(value)
+		case "lang", "language":
+			query.lang = strings.ToLower(unquote(value))
+		case "unique":
+			query.unique = strings.ToLower(value)
+		case "order":
+			query.order = strings.ToLower(value)
+		case "direction":
+			query.dir = strings.ToLower(value)
+		}
+	}
+
+	if len(query.exact) == 0 && len(query.words) == 0 && len(query.patterns) == 0 && query.set == "" && query.number == "" {
+		return query, errors.New("the query has no name, set or collector number")
+	}
+	return query, nil
+}
+
+// splitScryfallQuery splits the query at whitespace outside of quotes and name patterns.
+func splitScryfallQuery(q string) []string {
+	var tokens []string
+	var token strings.Builder
+	var quote rune
+	for _, r := range q {
+		switch {
+		case quote != 0:
+			token.WriteRune(r)
+			if r == quote {
+				quote = 0
+			}
+		case r == '"' || (r == '/' && strings.HasSuffix(token.String(), ":")):
+			token.WriteRune(r)
+			quote = r
+		case r == ' ' || r == '\t' || r == '\n':
+			if token.Len() > 0 {
+				tokens = append(tokens, token.String())
+				token.Reset()
+			}
+		default:
+			token.WriteRune(r)
+		}
+	}
+	if token.Len() > 0 {
+		tokens = append(tokens, token.String())
+	}
+	return tokens
+}
+
+func unquote(s string) string {
+	if len(s) >= 2 && s[0] == '"' && s[len(s)-1] == '"' {
+		return s[1 : len(s)-1]
+	}
+	return s
+}
+
+func (q *scryfallQuery) matches(card *scryfallCard) bool {
+	if q.set != "" && !strings.EqualFold(card.Set, q.set) {
+		return false
+	}
+	if q.number != "" {
+		want, wantSuffix := splitCollectorNumber(q.number)
+		got, gotSuffix := splitCollectorNumber(card.CollectorNumber)
+		if want != got || wantSuffix != gotSuffix {
+			return false
+		}
+	}
+	if q.lang != "" && q.lang != "any" && !strings.EqualFold(card.Lang, q.lang) {
+		return false
+	}
+
+	for _, exact := range q.exact {
+		if !slices.Contains(card.names, exact) {
+			return false
+		}
+	}
+	for _, word := range q.words {
+		if !strings.Contains(card.names[0], word) {
+			return false
+		}
+	}
+	for _, pattern := range q.patterns {
+		matched := pattern.MatchString(card.Name)
+		for _, face := range card.CardFaces {
+			matched = matched || pattern.MatchString(face.Name)
+		}
+		if !matched {
+			return false
+		}
+	}
+	return true
+}
+
+// search returns the matching cards, sorted and deduplicated like the Scryfall API.
+func (d *ScryfallData) search(query scryfallQuery) []*scryfallCard {
+	candidates := d.cards
+	if len(query.exact) > 0 {
+		// The exact name narrows the search down to a few printings
+		candidates = nil
+		for _, i := range d.byName[query.exact[0]] {
+			candidates = append(candidates, d.cards[i])
+		}
+	}
+
+	var found []*scryfallCard
+	for i := range candidates {
+		if query.matches(&candidates[i]) {
+			found = append(found, &candidates[i])
+		}
+	}
+
+	switch query.order {
+	case "released":
+		slices.SortStableFunc(found, func(a, b *scryfallCard) int {
+			return cmp.Compare(b.ReleasedAt, a.ReleasedAt)
+		})
+	case "set":
+		slices.SortStableFunc(found, func(a, b *scryfallCard) int {
+			return cmp.Or(cmp.Compare(a.Set, b.Set), compareCollectorNumbers(a.CollectorNumber, b.CollectorNumber))
+		})
+	default:
+		slices.SortStableFunc(found, func(a, b *scryfallCard) int {
+			return cmp.Compare(a.Name, b.Name)
+		})
+	}
+	// Released is sorted newest first, the others ascending
+	if (query.order == "released" && query.dir == "asc") || (query.order != "released" && query.dir == "desc") {
+		slices.Reverse(found)
+	}
+
+	var key func(*scryfallCard) string
+	switch query.unique {
+	case "prints":
+		return found
+	case "art":
+		key = (*scryfallCard).illustration
+	default:
+		key = func(c *scryfallCard) string { return cmp.Or(c.OracleID, c.Name) }
+	}
+	seen := make(map[string]bool)
+	unique := found[:0]
+	for _, card := range found {
+		if k := key(card); k == "" || !seen[k] {
+			seen[k] = true
+			unique = append(unique, card)
+		}
+	}
+	return unique
+}
+
+func compareCollectorNumbers(a, b string) int {
+	numberA, suffixA := splitCollectorNumber(a)
+	numberB, suffixB := splitCollectorNumber(b)
+	return cmp.Or(cmp.Compare(len(numberA), len(numberB)), cmp.Compare(numberA, numberB), cmp.Compare(suffixA, suffixB))
+}
+
+// serve answers a Scryfall API request, requests it doesn't know continue to the network.
+func (d *ScryfallData) serve(req *network.Request) *fetchResponse {
+	if req.Method != http.MethodGet {
+		return nil
+	}
+	u, err := url.Parse(req.URL)
+	if err != nil {
+		return nil
+	}
+	_, endpoint, ok := strings.Cut(u.Path, "/cards/")
+	if !ok {
+		return nil
+	}
+	params := u.Query()
+
+	switch endpoint {
+	case "search":
+		query, err := parseScryfallQuery(params.Get("q"))
+		if err != nil {
+			return scryfallError(http.StatusBadRequest, "bad_request", err.Error())
+		}
+		query.unique = cmp.Or(params.Get("unique"), query.unique)
+		query.order = cmp.Or(params.Get("order"), query.order)
+		query.dir = cmp.Or(params.Get("dir"), query.dir)
+
+		cards := d.search(query)
+		if len(cards) == 0 {
+			return scryfallError(http.StatusNotFound, "not_found", "Your query didn't match any cards.")
+		}
+		data := make([]json.RawMessage, 0, len(cards))
+		for _, card := range cards {
+			data = append(data, card.raw)
+		}
+		return scryfallJSON(http.StatusOK, map[string]any{
+			"object":      "list",
+			"total_cards": len(data),
+			"has_more":    false,
+			"data":        data,
+		})
+
+	case "named":
+		query := scryfallQuery{set: strings.ToLower(params.Get("set")), unique: "prints", order: "released"}
+		if exact := params.Get("exact"); exact != "" {
+			query.exact = []string{normalizeName(exact)}
+		} else if fuzzy := params.Get("fuzzy"); fuzzy != "" {
+			query.words = strings.Fields(normalizeName(fuzzy))
+		} else {
+			return scryfallError(http.StatusBadRequest, "bad_request", "exact or fuzzy is required")
+		}
+		return d.single(query)
+	}
+
+	// /cards/:set/:number and /cards/:set/:number/:lang
+	parts := strings.Split(endpoint, "/")
+	if len(parts) < 2 || len(parts) > 3 {
+		return nil
+	}
+	query := scryfallQuery{set: strings.ToLower(parts[0]), number: parts[1], unique: "prints", order: "released"}
+	if len(parts) == 3 {
+		query.lang = parts[2]
+	}
+	return d.single(query)
+}
+
+// single answers with the newest matching card.
+func (d *ScryfallData) single(query scryfallQuery) *fetchResponse {
+	cards := d.search(query)
+	if len(cards) == 0 {
+		return scryfallError(http.StatusNotFound, "not_found", "No card found with the given name or printing.")
+	}
+	return &fetchResponse{Status: http.StatusOK, Headers: scryfallHeaders(), Body: cards[0].raw}
+}
+
+func scryfallHeaders() map[string]string {
+	return map[string]string{
+		"Content-Type": "application/json; charset=utf-8",
+		// The page requests the API cross origin
+		"Access-Control-Allow-Origin": "*",
+	}
+}
+
+func scryfallJSON(status int, v any) *fetchResponse {
+	body, err := json.Marshal(v)
+	if err != nil {
+		return scryfallError(http.StatusInternalServerError, "internal_error", err.Error())
+	}
+	return &fetchResponse{Status: status, Headers: scryfallHeaders(), Body: body}
+}
+
+func scryfallError(status int, code, details string) *fetchResponse {
+	body, _ := json.Marshal(map[string]any{"object": "error", "code": code, "status": status, "details": details})
+	return &fetchResponse{Status: status, Headers: scryfallHeaders(), Body: body}
+}
@@ -0,0 +1,136 @@
+package cardconjurer
+
+import (
+	"encoding/json"
+	"github.com/chromedp/cdproto/network"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+const bulkData = `[
+	{"name": "Sol Ring", "set": "ltc", "collector_number": "3", "lang": "en", "released_at": "2023-06-23", "oracle_id": "sol"},
+	{"name": "Sol Ring", "set": "c21", "collector_number": "263", "lang": "en", "released_at": "2021-04-23", "oracle_id": "sol"},
+	{"name": "Sol Ring", "set": "c21", "collector_number": "263", "lang": "de", "released_at": "2021-04-23", "oracle_id": "sol"},
+	{"name": "Solemn Simulacrum", "set": "c21", "collector_number": "254", "lang": "en", "released_at": "2021-04-23", "oracle_id": "solemn"},
+	{"name": "Delver of Secrets // Insectile Aberration", "set": "isd", "collector_number": "51", "lang": "en", "released_at": "2011-09-30", "oracle_id": "delver",
+		"card_faces": [{"name": "Delver of Secrets"}, {"name": "Insectile Aberration"}]},
+	{"name": "Jötun Grunt", "set": "csp", "collector_number": "8", "lang": "en", "released_at": "2006-07-21", "oracle_id": "jotun"}
+]`
+
+func loadTestScryfallData(t *testing.T) *ScryfallData {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "default-cards.json")
+	if err := os.WriteFile(path, []byte(bulkData), 0644); err != nil {
+		t.Fatal(err)
+	}
+	d, err := LoadScryfallData(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return d
+}
+
+func TestScryfallSearch(t *testing.T) {
+	d := loadTestScryfallData(t)
+
+	tests := []struct {
+		name   string
+		url    string
+		status int
+		want   []string
+	}{
+		{"name", "/cards/search?q=Sol+Ring&unique=prints&order=released", 200, []string{"Sol Ring ltc 3 en", "Sol Ring c21 263 en", "Sol Ring c21 263 de"}},
+		{"unique cards", "/cards/search?q=Sol+Ring", 200, []string{"Sol Ring ltc 3 en"}},
+		{"released ascending", "/cards/search?q=lang:en+Sol+Ring&unique=prints&order=released&dir=asc", 200, []string{"Sol Ring c21 263 en", "Sol Ring ltc 3 en"}},
+		{"words", "/cards/search?q=sol&unique=prints&order=set", 200, []string{"Solemn Simulacrum c21 254 en", "Sol Ring c21 263 en", "Sol Ring c21 263 de", "Sol Ring ltc 3 en"}},
+		{"exact", `/cards/search?q=!"sol ring"+set:c21+cn:263+lang:en`, 200, []string{"Sol Ring c21 263 en"}},
+		{"exact face", `/cards/search?q=!"Delver of Secrets"`, 200, []string{"Delver of Secrets // Insectile Aberration isd 51 en"}},
+		{"name equals", `/cards/search?q=name%3D"Sol+Ring"+lang%3Den&unique=prints`, 200, []string{"Sol Ring ltc 3 en", "Sol Ring c21 263 en"}},
+		{"name pattern", "/cards/search?q=name:/^sol r/+set:ltc", 200, []string{"Sol Ring ltc 3 en"}},
+		{"accents", `/cards/search?q=!"Jotun Grunt"`, 200, []string{"Jötun Grunt csp 8 en"}},
+		{"leading zeros", "/cards/search?q=set:csp+cn:008", 200, []string{"Jötun Grunt csp 8 en"}},
+		{"ignored filters", "/cards/search?q=Sol+Ring+-is:digital+game:paper+cmc>0+set:ltc", 200, []string{"Sol Ring ltc 3 en"}},
+		{"no match", "/cards/search?q=Black+Lotus", 404, nil},
+		{"empty query", "/cards/search?q=", 400, nil},
+		{"named", "/cards/named?exact=Sol+Ring&set=c21", 200, []string{"Sol Ring c21 263 en"}},
+		{"named fuzzy", "/cards/named?fuzzy=insectile", 200, []string{"Delver of Secrets // Insectile Aberration isd 51 en"}},
+		{"printing", "/cards/c21/263/de", 200, []string{"Sol Ring c21 263 de"}},
+		{"unknown printing", "/cards/c21/999", 404, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := d.serve(&network.Request{Method: http.MethodGet, URL: "https://api.scryfall.com" + tt.url})
+			if resp == nil {
+				t.Fatal("request was not answered")
+			}
+			if resp.Status != tt.status {
+				t.Fatalf("status %d, expected %d: %s", resp.Status, tt.status, resp.Body)
+			}
+			if resp.Headers["Access-Control-Allow-Origin"] != "*" {
+				t.Error("response is missing the CORS header")
+			}
+			if tt.status != http.StatusOK {
+				return
+			}
+
+			var cards []scryfallCard
+			var list struct {
+				Object string            `json:"object"`
+				Data   []json.RawMessage `json:"data"`
+			}
+			if err := json.Unmarshal(resp.Body, &list); err != nil {
+				t.Fatal(err)
+			}
+			if list.Object == "list" {
+				for _, raw := range list.Data {
+					var card scryfallCard
+					json.Unmarshal(raw, &card)
+					cards = append(cards, card)
+				}
+			} else {
+				var card scryfallCard
+				json.Unmarshal(resp.Body, &card)
+				cards = append(cards, card)
+			}
+
+			var got []string
+			for _, card := range cards {
+				got = append(got, card.Name+" "+card.Set+" "+card.CollectorNumber+" "+card.Lang)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("got %q, expected %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestScryfallPassesUnknownRequests(t *testing.T) {
+	d := loadTestScryfallData(t)
+	for _, req := range []*network.Request{
+		{Method: http.MethodGet, URL: "https://api.scryfall.com/sets/c21"},
+		{Method: http.MethodPost, URL: "https://api.scryfall.com/cards/collection"},
+	} {
+		if resp := d.serve(req); resp != nil {
+			t.Errorf("%s %s was answered with %d", req.Method, req.URL, resp.Status)
+		}
+	}
+}
+
+func TestURLPatternRegexp(t *testing.T) {
+	pattern := urlPatternRegexp(DefaultScryfallURLPattern)
+	for u, want := range map[string]bool{
+		"https://api.scryfall.com/cards/search?q=" + url.QueryEscape("Sol Ring"): true,
+		"https://api.scryfall.com/cards/c21/263":                                 true,
+		"https://api.scryfall.com/sets/c21":                                      false,
+		"https://api.scryfall.com.evil.example/cards/":                           false,
+	} {
+		if got := pattern.MatchString(u); got != want {
+			t.Errorf("%s matched %v, expected %v", u, got, want)
+		}
+	}
+}
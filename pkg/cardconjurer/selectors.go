@@ -0,0 +1,137 @@
+package cardconjurer
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+	"time"
+)
+
+const selectorsVersion = 1
+
+//go:embed selectors/default.json
+var defaultSelectorsJSON []byte
+
+// Selectors holds every CSS selector and magic value used to drive the Card Conjurer UI,
+// so UI changes only need a new profile instead of a new binary.
+type Selectors struct {
+	// Version is the format version of the profile
+	Version int `json:"version"`
+	// Name describes the Card Conjurer version the profile was written for
+	Name string `json:"name"`
+
+	// Tab is the selector of a creator tab, %s is replaced by the tab name
+	Tab  string `json:"tab"`
+	Tabs struct {
+		Import    string `json:"import"`
+		Frame     string `json:"frame"`
+		Art       string `json:"art"`
+		SetSymbol string `json:"set_symbol"`
+	} `json:"tabs"`
+
+	ImportName      string `json:"import_name"`
+	ImportIndex     string `json:"import_index"`
+	ImportAllPrints string `json:"import_all_prints"`
+	AutoFrame       string `json:"auto_frame"`
+
+	FrameGroup string `json:"frame_group"`
+	// MarginFrameGroup is the value of the frame group holding the margin
+	MarginFrameGroup string `json:"margin_frame_group"`
+	AddToFull        string `json:"add_to_full"`
+	MarginThumbnail  string `json:"margin_thumbnail"`
+
+	ArtUpload       string `json:"art_upload"`
+	RemoveSetSymbol string `json:"remove_set_symbol"`
+	Download        string `json:"download"`
+
+	PreviewCanvas string `json:"preview_canvas"`
+	// CardCanvasVariable is the global JavaScript variable holding the full size card canvas
+	CardCanvasVariable string `json:"card_canvas_variable"`
+	// ScryfallCardsVariable is the global JavaScript variable holding the Scryfall data of the
+	// search results, indexed by the option values of ImportIndex
+	ScryfallCardsVariable string `json:"scryfall_cards_variable"`
+
+	PageLoadDelayMs  int `json:"page_load_delay_ms"`
+	SetSymbolDelayMs int `json:"set_symbol_delay_ms"`
+}
+
+// embeddedSelectors is used if Config.Selectors is nil
+var embeddedSelectors = DefaultSelectors()
+
+// DefaultSelectors returns the embedded profile for the current Card Conjurer.
+func DefaultSelectors() *Selectors {
+	s := &Selectors{}
+	if err := json.Unmarshal(defaultSelectorsJSON, s); err != nil {
+		panic(fmt.Sprintf("invalid embedded selector profile: %v", err))
+	}
+	return s
+}
+
+// LoadSelectors reads a profile. Keys missing in the file keep their default value.
+func LoadSelectors(path string) (*Selectors, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	s := DefaultSelectors()
+	if err := json.Unmarshal(data, s); err != nil {
+		return nil, fmt.Errorf("error parsing selector profile %s: %v", path, err)
+	}
+	if err := s.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid selector profile %s: %v", path, err)
+	}
+	return s, nil
+}
+
+func (s *Selectors) Validate() error {
+	if s.Version != selectorsVersion {
+		return fmt.Errorf("unsupported profile version %d, expected %d", s.Version, selectorsVersion)
+	}
+	if !strings.Contains(s.Tab, "%s") {
+		return fmt.Errorf("tab selector %q must contain %%s for the tab name", s.Tab)
+	}
+
+	var empty []string
+	collectEmptyStrings(reflect.ValueOf(*s), "", &empty)
+	if len(empty) > 0 {
+		return fmt.Errorf("empty values: %s", strings.Join(empty, ", "))
+	}
+	return nil
+}
+
+func collectEmptyStrings(v reflect.Value, prefix string, empty *[]string) {
+	for i := 0; i < v.NumField(); i++ {
+		name := prefix + strings.Split(v.Type().Field(i).Tag.Get("json"), ",")[0]
+		switch field := v.Field(i); field.Kind() {
+		case reflect.String:
+			if field.String() == "" {
+				*empty = append(*empty, name)
+			}
+		case reflect.Struct:
+			collectEmptyStrings(field, name+".", empty)
+		}
+	}
+}
+
+// tab returns the selector of the creator tab with the given name.
+func (s *Selectors) tab(name string) string {
+	return fmt.Sprintf(s.Tab, name)
+}
+
+func (s *Selectors) pageLoadDelay() time.Duration {
+	return time.Duration(s.PageLoadDelayMs) * time.Millisecond
+}
+
+func (s *Selectors) setSymbolDelay() time.Duration {
+	return time.Duration(s.SetSymbolDelayMs) * time.Millisecond
+}
+
+// jsString quotes the string for use as a JavaScript string literal.
+func jsString(s string) string {
+	quoted, _ := json.Marshal(s)
+	return string(quoted)
+}
@@ -0,0 +1,123 @@
+package cardconjurer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go.uber.org/zap"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// DefaultSitePage is the creator page in a Card Conjurer checkout.
+const DefaultSitePage = "creator/"
+
+func init() {
+	// Not part of Go's builtin table, without them fonts depend on the system's mime.types
+	for ext, typ := range map[string]string{
+		".otf":   "font/otf",
+		".ttf":   "font/ttf",
+		".woff":  "font/woff",
+		".woff2": "font/woff2",
+	} {
+		mime.AddExtensionType(ext, typ)
+	}
+}
+
+// LocalSite serves a local Card Conjurer checkout on a free loopback port. Files are served
+// by http.FileServer, so content types follow the file extension and range requests work.
+type LocalSite struct {
+	// URL is the root of the site, e.g. http://127.0.0.1:41234
+	URL string
+
+	server *http.Server
+	done   chan struct{}
+}
+
+// ServeSite starts serving the directory, stop it with Close.
+func ServeSite(dir string, logger *zap.SugaredLogger) (*LocalSite, error) {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return nil, fmt.Errorf("error reading site directory: %v", err)
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("site directory %s is not a directory", dir)
+	}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, fmt.Errorf("error listening on a loopback port: %v", err)
+	}
+
+	site := &LocalSite{
+		URL: "http://" + listener.Addr().String(),
+		server: &http.Server{
+			Handler:           http.FileServer(http.Dir(dir)),
+			ReadHeaderTimeout: 10 * time.Second,
+		},
+		done: make(chan struct{}),
+	}
+	go func() {
+		defer close(site.done)
+		if err := site.server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logger.Errorw("Site server stopped", "dir", dir, "error", err)
+		}
+	}()
+
+	logger.Infof("Serving %s at %s", dir, site.URL)
+	return site, nil
+}
+
+// Close stops the server, requests in flight get a few seconds to finish.
+func (s *LocalSite) Close() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	err := s.server.Shutdown(ctx)
+	<-s.done
+	return err
+}
+
+// ServeSite serves Config.SiteDir and points BaseUrl at its creator page for the duration
+// of a run. The returned function stops the server and restores BaseUrl. Without a site
+// directory nothing is started.
+func (c *Config) ServeSite(logger *zap.SugaredLogger) (func(), error) {
+	if c.SiteDir == "" {
+		return func() {}, nil
+	}
+
+	page := c.SitePage
+	if page == "" {
+		page = DefaultSitePage
+	}
+	if _, err := os.Stat(filepath.Join(c.SiteDir, filepath.FromSlash(page))); err != nil {
+		return nil, fmt.Errorf("no Card Conjurer page %s in site directory %s: %v", page, c.SiteDir, err)
+	}
+	if len(c.Browser.RemoteURLs) > 0 {
+		logger.Warn("The site is served on a loopback port, remote browsers must run on this machine to reach it")
+	}
+
+	site, err := ServeSite(c.SiteDir, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	baseUrl := c.BaseUrl
+	c.BaseUrl, err = url.JoinPath(site.URL, page)
+	if err != nil {
+		site.Close()
+		return nil, fmt.Errorf("invalid site page %q: %v", page, err)
+	}
+	c.siteURL = site.URL
+	return func() {
+		if err := site.Close(); err != nil {
+			logger.Warnw("Error stopping site server", "error", err)
+		}
+		c.BaseUrl = baseUrl
+		c.siteURL = ""
+	}, nil
+}
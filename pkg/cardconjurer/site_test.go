@@ -0,0 +1,92 @@
+package cardconjurer
+
+import (
+	"go.uber.org/zap/zaptest"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestServeSite(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"creator/index.html":    "<html></html>",
+		"js/creator-23.js":      "var cardCanvas;",
+		"fonts/beleren-b.woff2": "wOF2",
+		"img/frames/margin.png": "\x89PNG\r\n\x1a\n0123456789",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cfg := &Config{SiteDir: dir}
+	stop, err := cfg.ServeSite(zaptest.NewLogger(t).Sugar())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := validateBaseUrl(cfg.BaseUrl); err != nil {
+		t.Fatal(err)
+	}
+
+	get := func(path, rng string) *http.Response {
+		t.Helper()
+		url := cfg.BaseUrl + "../" + path
+		req, err := http.NewRequest(http.MethodGet, url, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if rng != "" {
+			req.Header.Set("Range", rng)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { resp.Body.Close() })
+		return resp
+	}
+
+	for path, want := range map[string]string{
+		"creator/":              "text/html; charset=utf-8",
+		"js/creator-23.js":      "text/javascript; charset=utf-8",
+		"fonts/beleren-b.woff2": "font/woff2",
+		"img/frames/margin.png": "image/png",
+	} {
+		resp := get(path, "")
+		if resp.StatusCode != http.StatusOK {
+			t.Errorf("%s: status %d", path, resp.StatusCode)
+		}
+		if got := resp.Header.Get("Content-Type"); got != want {
+			t.Errorf("%s: content type %q, expected %q", path, got, want)
+		}
+	}
+
+	resp := get("img/frames/margin.png", "bytes=8-11")
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusPartialContent || string(body) != "0123" {
+		t.Errorf("range request returned %d %q, expected 206 \"0123\"", resp.StatusCode, body)
+	}
+
+	stop()
+	if cfg.BaseUrl != "" {
+		t.Errorf("base url %q was not restored", cfg.BaseUrl)
+	}
+}
+
+func TestServeSiteWithoutCreator(t *testing.T) {
+	cfg := &Config{SiteDir: t.TempDir()}
+	if _, err := cfg.ServeSite(zaptest.NewLogger(t).Sugar()); err == nil {
+		t.Fatal("expected an error for a directory without the creator page")
+	}
+}
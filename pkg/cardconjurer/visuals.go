@@ -4,54 +4,47 @@ import (
 	"cardconjurer-automation/pkg/common"
 	"context"
 	"fmt"
-	"github.com/chromedp/chromedp"
 	"os"
 	"time"
 )
 
 func (w *worker) addMargin(browserCtx context.Context) error {
+	sel := w.config.selectors()
+
 	// Click on the frame tab and wait for the dropdown to be visible
 	w.logger.Info("Starting margin import")
-	if err := chromedp.Run(browserCtx,
-		chromedp.Click(`h3.selectable.readable-background[onclick*="toggleCreatorTabs"][onclick*="frame"]`),
-		chromedp.WaitVisible(`#selectFrameGroup`, chromedp.ByID),
-	); err != nil {
+	if err := w.openTab(browserCtx, sel.Tabs.Frame, sel.FrameGroup); err != nil {
 		return err
 	}
 
-	// Select "Margin" in the dropdown and wait for the button to be ready
-	w.logger.Info("Selecting 'Margin' in frame dropdown")
-	if err := chromedp.Run(browserCtx,
-		chromedp.SetValue(`#selectFrameGroup`, "Margin"),
-		chromedp.WaitReady(`#addToFull`, chromedp.ByID),
-	); err != nil {
+	// Select the margin group in the dropdown and wait for the button to be ready
+	w.logger.Infof("Selecting '%s' in frame dropdown", sel.MarginFrameGroup)
+	if err := w.driver.SetValue(browserCtx, sel.FrameGroup, sel.MarginFrameGroup); err != nil {
+		return err
+	}
+	if err := w.driver.WaitReady(browserCtx, sel.AddToFull); err != nil {
 		return err
 	}
 
 	// Wait for the desired image element to load
 	w.logger.Info("Waiting for margin image element")
-	if err := chromedp.Run(browserCtx,
-		chromedp.WaitReady(`img[src="/img/frames/margins/blackBorderExtensionThumb.png"]`),
-	); err != nil {
+	if err := w.driver.WaitReady(browserCtx, sel.MarginThumbnail); err != nil {
 		return err
 	}
 
 	w.logger.Info("Clicking 'addToFull' button")
-	if err := chromedp.Run(browserCtx,
-		chromedp.Click(`#addToFull`),
-	); err != nil {
+	if err := w.driver.Click(browserCtx, sel.AddToFull); err != nil {
 		return err
 	}
 
-	// After clicking 'addToFull': wait for the canvas element (#previewCanvas) to change.
+	// After clicking 'addToFull': wait for the preview canvas to change.
 	// Since the canvas cannot be directly compared, you can observe e.g. the size, an attribute or a hash of the image content.
 	// Here: Read a DataURL snapshot before the click and wait until it changes.
 
 	w.logger.Info("Waiting for canvas to update after 'addToFull'")
+	canvas := jsString(sel.PreviewCanvas)
 	var oldDataURL string
-	if err := chromedp.Run(browserCtx,
-		chromedp.Evaluate(`document.getElementById('previewCanvas')?.toDataURL()`, &oldDataURL),
-	); err != nil {
+	if err := w.driver.Evaluate(browserCtx, fmt.Sprintf(`document.querySelector(%s)?.toDataURL()`, canvas), &oldDataURL); err != nil {
 		w.logger.Warnf("Could not read canvas DataURL: %v", err)
 		// not a fatal error, continue
 	}
@@ -59,14 +52,10 @@ func (w *worker) addMargin(browserCtx context.Context) error {
 	if oldDataURL != "" {
 		ctx, cancel := context.WithTimeout(browserCtx, 10*time.Second)
 		defer cancel()
-		var newDataURL string
-		err := chromedp.Run(ctx,
-			chromedp.Poll(`(() => {
-				const c = document.getElementById('previewCanvas');
-				return c && c.toDataURL() !== "`+oldDataURL+`";
-			})()`, nil, chromedp.WithPollingInterval(200*time.Millisecond)),
-			chromedp.Evaluate(`document.getElementById('previewCanvas')?.toDataURL()`, &newDataURL),
-		)
+		err := w.driver.Poll(ctx, fmt.Sprintf(`(() => {
+				const c = document.querySelector(%s);
+				return c && c.toDataURL() !== %s;
+			})()`, canvas, jsString(oldDataURL)), 200*time.Millisecond)
 		if err != nil {
 			w.logger.Warnf("Timeout or error while waiting for canvas update: %v", err)
 		} else {
@@ -80,64 +69,62 @@ func (w *worker) addMargin(browserCtx context.Context) error {
 	return nil
 }
 
-func (w *worker) replaceArtwork(card common.CardInfo, browserCtx context.Context) error {
+// replaceArtwork uploads the artwork of the card if there is one and returns its path.
+func (w *worker) replaceArtwork(card common.CardInfo, browserCtx context.Context) (string, error) {
 	if w.config.InputArtworkFolder == "" {
 		//return nil
 	}
 
 	// Click on the artwork tab and wait for the file input to be visible
 	w.logger.Info("Starting artwork import")
-	inputSelector := `input[type="file"][accept*=".png"][data-dropfunction="uploadArt"]`
+	inputSelector := w.config.selectors().ArtUpload
 	err := w.openTab(
 		browserCtx,
-		"art",
+		w.config.selectors().Tabs.Art,
 		inputSelector,
 	)
 	if err != nil {
-		return err
+		return "", err
 	}
 
 	// Check if a matching PNG file exists in the artwork folder
-	filename := fmt.Sprintf("%s.png", card.GetName())
-	filepath := fmt.Sprintf("%s/%s", w.config.InputArtworkFolder, filename)
+	filepath := w.config.artworkPath(card)
 	if _, err := os.Stat(filepath); err != nil {
 		if os.IsNotExist(err) {
 			w.logger.Infof("Artwork file not found: %s", filepath)
-			return nil
+			return "", nil
 		}
 
-		return err
+		return "", err
 	}
 
 	w.logger.Infof("Artwork file found: %s", filepath)
 	// Set the file path as value for the file input
-	if err := chromedp.Run(browserCtx,
-		chromedp.SetUploadFiles(inputSelector, []string{filepath}),
-	); err != nil {
+	if err := w.driver.Upload(browserCtx, inputSelector, filepath); err != nil {
 		w.logger.Warnf("Error setting artwork file: %v", err)
-		return err
+		return "", err
 	}
 	w.logger.Infof("Artwork file %s set successfully.", filepath)
 
-	return nil
+	return filepath, nil
 }
 
 func (w *worker) removeSetSymbol(browserCtx context.Context) error {
-	//buttonSelector := `button.input.margin-bottom[onclick*="removeSetSymbol()"]`
-	buttonSelector := `#creator-menu-setSymbol > div:nth-child(3) > button`
+	sel := w.config.selectors()
+	buttonSelector := sel.RemoveSetSymbol
 
-	err := w.openTab(browserCtx, "setSymbol", buttonSelector)
+	err := w.openTab(browserCtx, sel.Tabs.SetSymbol, buttonSelector)
 	if err != nil {
 		return err
 	}
 
 	// Click the button to remove the set symbol
-	if err := chromedp.Run(browserCtx,
-		chromedp.Click(buttonSelector),
-		chromedp.Sleep(250*time.Millisecond),
-	); err != nil {
+	if err := w.driver.Click(browserCtx, buttonSelector); err != nil {
 		return err
 	}
+	if !sleep(browserCtx, sel.setSymbolDelay()) {
+		return browserCtx.Err()
+	}
 
 	return nil
 }
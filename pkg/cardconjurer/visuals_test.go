@@ -0,0 +1,64 @@
+package cardconjurer
+
+import (
+	"cardconjurer-automation/pkg/decklist_parser"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestReplaceArtwork(t *testing.T) {
+	sel := DefaultSelectors()
+	card := &decklist_parser.Card{Count: 1, Name: "Sol Ring"}
+
+	tests := []struct {
+		name    string
+		artwork bool
+		missing string
+		upload  bool
+		wantErr bool
+	}{
+		{name: "artwork", artwork: true, upload: true},
+		{name: "no artwork"},
+		{name: "missing art tab", artwork: true, missing: sel.tab(sel.Tabs.Art), wantErr: true},
+		{name: "missing file input", artwork: true, missing: sel.ArtUpload, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{InputArtworkFolder: t.TempDir()}
+			artwork := cfg.artworkPath(card)
+			if tt.artwork {
+				if err := os.WriteFile(artwork, pngSignature, 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			d := newFakeDriver()
+			delete(d.elements, tt.missing)
+			w := newTestWorker(t, cfg, d)
+
+			path, err := w.replaceArtwork(card, context.Background())
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			uploaded, ok := d.uploads[sel.ArtUpload]
+			if ok != tt.upload {
+				t.Fatalf("uploaded %v, expected %v", ok, tt.upload)
+			}
+			if tt.upload && (uploaded != artwork || filepath.Clean(path) != filepath.Clean(artwork)) {
+				t.Errorf("uploaded %s and returned %s, expected %s", uploaded, path, artwork)
+			}
+			if !tt.upload && path != "" {
+				t.Errorf("returned %s without artwork", path)
+			}
+		})
+	}
+}
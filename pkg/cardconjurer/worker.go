@@ -3,6 +3,7 @@ package cardconjurer
 import (
 	"cardconjurer-automation/pkg/common"
 	"context"
+	"errors"
 	"fmt"
 	"go.uber.org/zap"
 	"time"
@@ -12,28 +13,45 @@ type worker struct {
 	workerID    int
 	config      *Config
 	tempDirName string
+	// profileDir and downloadDir belong to the current browser session
+	profileDir  string
+	downloadDir string
+	pipeline    []step
 	logger      *zap.SugaredLogger
+	driver      driver
+
+	browserCtx          context.Context
+	cancelBrowser       func()
+	consecutiveFailures int
+	// onResult is called with the outcome of every card
+	onResult func(CardReport)
+	// resolutions are the printings found by the resolve phase, read only while rendering
+	resolutions map[common.CardInfo]resolution
+	// scryfall answers the card data requests of the page in offline mode
+	scryfall *ScryfallData
+	// cache records or replays the network traffic of the page
+	cache *NetworkCache
 }
 
-func newWorker(workerID int, logger *zap.SugaredLogger, config *Config) *worker {
+func newWorker(workerID int, logger *zap.SugaredLogger, config *Config, pipeline []step) *worker {
 	return &worker{
 		workerID:    workerID,
 		config:      config,
+		pipeline:    pipeline,
 		tempDirName: fmt.Sprintf("%s_%d", config.ProjectName, workerID),
 		logger:      logger.With("worker_id", workerID),
+		driver:      &chromedpDriver{remote: config.Browser.remoteURL(workerID) != ""},
 	}
 }
 
-func (w *worker) startWorker(ctx context.Context, cardsChan <-chan common.CardInfo, outputChan chan<- common.CardInfo) {
-	browserCtx, err := w.openBrowser(ctx)
-	if err != nil {
-		w.logger.Errorw("Error opening browser", "error", err)
-		return
-	}
-
+// startWorker processes cards until cardsChan is closed or ctx is cancelled. Browsers are
+// started from browserParent, so a card in progress can finish after ctx is cancelled.
+func (w *worker) startWorker(ctx, browserParent context.Context, cardsChan <-chan common.CardInfo, outputChan chan<- common.CardInfo) {
 	defer func() {
-		w.closeBrowser(browserCtx)
-		w.logger.Info("Browser closed")
+		if w.browserCtx != nil {
+			w.closeBrowser(w.browserCtx)
+			w.browserCtx = nil
+		}
 	}()
 
 	for {
@@ -47,8 +65,12 @@ func (w *worker) startWorker(ctx context.Context, cardsChan <-chan common.CardIn
 				return
 			}
 
-			err := w.handleCard(card, browserCtx)
-			if err != nil {
+			result := w.processCard(ctx, browserParent, card)
+			if w.onResult != nil {
+				w.onResult(result)
+			}
+			if result.Status != StatusSucceeded {
+				w.logger.Errorw("Card not rendered", "card", card.GetFullName(), "status", result.Status, "attempts", result.Attempts, "error", result.Error)
 				continue
 			}
 
@@ -60,45 +82,130 @@ func (w *worker) startWorker(ctx context.Context, cardsChan <-chan common.CardIn
 	}
 }
 
-func (w *worker) handleCard(card common.CardInfo, browserCtx context.Context) error {
-	oldLogger := w.logger
-	w.logger = w.logger.With("card", card.GetFullName())
+// processCard runs the pipeline for the card and retries it according to the retry policy.
+func (w *worker) processCard(ctx, browserParent context.Context, card common.CardInfo) (result CardReport) {
+	policy := w.config.Retry.withDefaults()
+	started := time.Now()
+	result = newCardReport(card, w.workerID)
 	defer func() {
-		w.logger = oldLogger
+		result.DurationMs = time.Since(started).Milliseconds()
 	}()
 
-	w.logger.Info("Processing card")
+	var err error
+	for result.Attempts < policy.MaxAttempts {
+		if ctx.Err() != nil {
+			break
+		}
+
+		if result.Attempts > 0 {
+			backoff := policy.backoff(result.Attempts)
+			w.logger.Infow("Retrying card", "card", card.GetFullName(), "attempt", result.Attempts+1, "backoff", backoff)
+			if !sleep(ctx, backoff) {
+				result.Status = StatusSkipped
+				result.Reason = "run cancelled"
+				return result
+			}
+
+			if policy.FreshBrowserAfter > 0 && result.Attempts >= policy.FreshBrowserAfter {
+				w.logger.Info("Card keeps failing, retrying with a fresh browser session")
+				w.restartBrowser()
+			}
+		}
+		result.Attempts++
+
+		// The browser is opened lazily, so a failed start is retried like a failed card
+		if w.browserCtx == nil {
+			w.browserCtx, err = w.openBrowser(browserParent)
+			if err != nil {
+				w.logger.Errorw("Error opening browser", "error", err)
+				w.browserCtx = nil
+				err = &StepError{Step: "browser", Err: err}
+				continue
+			}
+		}
 
-	err := w.importCard(card, browserCtx)
-	if err != nil {
-		w.logger.Errorw("Error importing card", "error", err)
-		return err
+		var state *cardState
+		state, err = w.handleCard(card, w.browserCtx)
+		if err == nil {
+			w.consecutiveFailures = 0
+			result.Status = StatusSucceeded
+			if saved, ok := state.result(StepSave); ok {
+				result.OutputPath = saved.output
+			}
+			result.option = state.option
+			result.Resolved = state.option.Text
+			return result
+		}
+
+		// The same frame is missing on every attempt
+		var frameErr *FrameError
+		if errors.As(err, &frameErr) {
+			break
+		}
+
+		w.consecutiveFailures++
+		if policy.RestartAfterFailures > 0 && w.consecutiveFailures >= policy.RestartAfterFailures {
+			w.logger.Warnf("%d consecutive failures, restarting browser", w.consecutiveFailures)
+			w.restartBrowser()
+			w.consecutiveFailures = 0
+		}
 	}
 
-	w.logger.Info("Card imported, adding margin")
-	err = w.addMargin(browserCtx)
-	if err != nil {
-		w.logger.Errorw("Error adding margin", "error", err)
-		return err
+	if ctx.Err() != nil {
+		result.Status = StatusSkipped
+		result.Reason = "run cancelled"
+		if err != nil {
+			result.Error = err.Error()
+		}
+		return result
 	}
 
-	err = w.replaceArtwork(card, browserCtx)
-	if err != nil {
-		w.logger.Errorw("Error replacing artwork", "error", err)
-		return err
+	result.Status = StatusFailed
+	result.Error = err.Error()
+	var stepErr *StepError
+	if errors.As(err, &stepErr) {
+		result.FailedStep = stepErr.Step
+		result.Error = stepErr.Err.Error()
 	}
+	return result
+}
 
-	err = w.removeSetSymbol(browserCtx)
-	if err != nil {
-		w.logger.Errorw("Error removing set symbol", "error", err)
-		return err
+// restartBrowser closes the current browser session, the next attempt opens a new one.
+func (w *worker) restartBrowser() {
+	if w.browserCtx == nil {
+		return
 	}
+	w.closeBrowser(w.browserCtx)
+	w.browserCtx = nil
+}
 
-	err = w.saveCard(card, browserCtx)
-	if err != nil {
-		w.logger.Errorw("Error saving card", "error", err)
-		return err
+func (w *worker) handleCard(card common.CardInfo, browserCtx context.Context) (*cardState, error) {
+	oldLogger := w.logger
+	w.logger = w.logger.With("card", card.GetFullName())
+	defer func() {
+		w.logger = oldLogger
+	}()
+
+	w.logger.Info("Processing card")
+
+	state := &cardState{
+		card:       card,
+		browserCtx: browserCtx,
+	}
+	for _, st := range w.pipeline {
+		w.logger.Infof("Running step %s", st.name)
+		started := time.Now()
+		output, err := st.run(w, state)
+		if err != nil {
+			w.logger.Errorw("Error in pipeline step", "step", st.name, "error", err)
+			return state, &StepError{Step: st.name, Err: err}
+		}
+		state.results = append(state.results, stepResult{
+			step:     st.name,
+			output:   output,
+			duration: time.Since(started),
+		})
 	}
 
-	return nil
+	return state, nil
 }
@@ -5,6 +5,13 @@ type CardInfo interface {
 	GetCount() int
 	GetName() string
 	GetSanitizedName() string
+	// GetPrintingID identifies the printing by name, set and collector number,
+	// e.g. "lightning_bolt_m10_146".
+	GetPrintingID() string
 	GetSet() string
 	GetCollectorNumber() string
+	// GetExtra returns an additional decklist column (e.g. "frame") by its header name.
+	GetExtra(key string) (string, bool)
+	// GetSources lists the decklist locations ("file:line") the entry was read from.
+	GetSources() []string
 }
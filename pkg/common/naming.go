@@ -0,0 +1,41 @@
+package common
+
+import (
+	"fmt"
+	"strings"
+)
+
+// NamingScheme decides how rendered card files and MPC entries are named.
+type NamingScheme string
+
+const (
+	// NamingPrinting uses name, set and collector number, so different printings
+	// of the same card do not overwrite each other
+	NamingPrinting NamingScheme = "printing"
+	// NamingName only uses the card name
+	NamingName NamingScheme = "name"
+)
+
+func ParseNamingScheme(s string) (NamingScheme, error) {
+	switch scheme := NamingScheme(strings.ToLower(strings.TrimSpace(s))); scheme {
+	case "":
+		return NamingPrinting, nil
+	case NamingPrinting, NamingName:
+		return scheme, nil
+	default:
+		return "", fmt.Errorf("unknown naming scheme %q, expected %q or %q", s, NamingPrinting, NamingName)
+	}
+}
+
+// CardFileID returns the identifier of the card used for file names and MPC ids.
+func CardFileID(card CardInfo, scheme NamingScheme) string {
+	if scheme == NamingName {
+		return card.GetSanitizedName()
+	}
+	return card.GetPrintingID()
+}
+
+// CardFileName returns the file name of the rendered card, e.g. "project_lightning_bolt_m10_146.png".
+func CardFileName(projectName string, card CardInfo, scheme NamingScheme) string {
+	return fmt.Sprintf("%s_%s.png", projectName, CardFileID(card, scheme))
+}
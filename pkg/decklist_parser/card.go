@@ -10,6 +10,27 @@ type Card struct {
 	Name            string
 	Set             string
 	CollectorNumber string
+	// Section is the deck section the card was listed in (e.g. "deck", "sideboard").
+	// It is empty for formats without sections.
+	Section string
+	// Extra holds CSV columns that are not mapped to a card field, keyed by
+	// their normalized header name.
+	Extra map[string]string
+	// Sources lists every decklist location the entry was read from.
+	Sources []Source
+}
+
+// Source is a decklist location a card was read from. Line is 1-based, 0 if unknown.
+type Source struct {
+	File string
+	Line int
+}
+
+func (s Source) String() string {
+	if s.Line <= 0 {
+		return s.File
+	}
+	return fmt.Sprintf("%s:%d", s.File, s.Line)
 }
 
 func (c *Card) String() string {
@@ -17,12 +38,33 @@ func (c *Card) String() string {
 }
 
 func (c *Card) GetFullName() string {
+	// Plain text decklists (e.g. MTGO) do not always carry a printing
+	if c.Set == "" {
+		return c.Name
+	}
+	if c.CollectorNumber == "" {
+		return fmt.Sprintf("%s (%s)", c.Name, strings.ToUpper(c.Set))
+	}
 	return fmt.Sprintf("%s (%s #%s)", c.Name, strings.ToUpper(c.Set), c.CollectorNumber)
 }
 
 func (c *Card) GetSanitizedName() string {
+	return sanitize(c.Name)
+}
+
+func (c *Card) GetPrintingID() string {
+	id := c.GetSanitizedName()
+	for _, part := range []string{c.Set, c.CollectorNumber} {
+		if sanitized := sanitize(part); sanitized != "" {
+			id += "_" + sanitized
+		}
+	}
+	return id
+}
+
+func sanitize(s string) string {
 	// Everything in lowercase
-	name := strings.ToLower(c.Name)
+	name := strings.ToLower(s)
 	// Replace spaces with underscores
 	name = strings.ReplaceAll(name, " ", "_")
 	// Replace typographic apostrophe with straight apostrophe
@@ -52,3 +94,16 @@ func (c *Card) GetSet() string {
 func (c *Card) GetCollectorNumber() string {
 	return c.CollectorNumber
 }
+
+func (c *Card) GetSources() []string {
+	sources := make([]string, 0, len(c.Sources))
+	for _, source := range c.Sources {
+		sources = append(sources, source.String())
+	}
+	return sources
+}
+
+func (c *Card) GetExtra(key string) (string, bool) {
+	value, ok := c.Extra[normalizeColumnName(key)]
+	return value, ok
+}
@@ -0,0 +1,216 @@
+package decklist_parser
+
+import (
+	"bytes"
+	"cardconjurer-automation/pkg/common"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// ColumnMapping lists the CSV header names that may hold each card field.
+// Names are compared case-insensitively, earlier names win.
+type ColumnMapping struct {
+	Count           []string
+	Name            []string
+	Set             []string
+	CollectorNumber []string
+}
+
+// DefaultColumnMapping covers the exports of Moxfield, Archidekt and Deckbox.
+var DefaultColumnMapping = ColumnMapping{
+	Count:           []string{"count", "quantity", "qty", "amount"},
+	Name:            []string{"name", "card name", "card"},
+	Set:             []string{"edition code", "set code", "set", "edition"},
+	CollectorNumber: []string{"collector number", "collector_number", "card number", "number", "cn"},
+}
+
+// ParseColumnMapping parses an override like "count=Qty,name=Card Name,set=Set,number=No".
+func ParseColumnMapping(s string) (ColumnMapping, error) {
+	var mapping ColumnMapping
+	if strings.TrimSpace(s) == "" {
+		return mapping, nil
+	}
+
+	for _, part := range strings.Split(s, ",") {
+		field, column, ok := strings.Cut(part, "=")
+		column = strings.TrimSpace(column)
+		if !ok || column == "" {
+			return mapping, fmt.Errorf("invalid column mapping %q, expected <field>=<column>", part)
+		}
+
+		switch normalizeColumnName(field) {
+		case "count", "quantity":
+			mapping.Count = append(mapping.Count, column)
+		case "name":
+			mapping.Name = append(mapping.Name, column)
+		case "set", "edition":
+			mapping.Set = append(mapping.Set, column)
+		case "number", "collector number", "collector_number", "cn":
+			mapping.CollectorNumber = append(mapping.CollectorNumber, column)
+		default:
+			return mapping, fmt.Errorf("unknown card field %q in column mapping, expected count, name, set or number", field)
+		}
+	}
+
+	return mapping, nil
+}
+
+// withDefaults fills all fields that were not overridden with the defaults.
+func (m ColumnMapping) withDefaults() ColumnMapping {
+	if len(m.Count) == 0 {
+		m.Count = DefaultColumnMapping.Count
+	}
+	if len(m.Name) == 0 {
+		m.Name = DefaultColumnMapping.Name
+	}
+	if len(m.Set) == 0 {
+		m.Set = DefaultColumnMapping.Set
+	}
+	if len(m.CollectorNumber) == 0 {
+		m.CollectorNumber = DefaultColumnMapping.CollectorNumber
+	}
+	return m
+}
+
+func normalizeColumnName(name string) string {
+	name = strings.TrimPrefix(name, "\ufeff")
+	return strings.ToLower(strings.TrimSpace(name))
+}
+
+// csvColumns holds the record index of each card field, -1 if the column is missing.
+type csvColumns struct {
+	count           int
+	name            int
+	set             int
+	collectorNumber int
+	// extra maps the record index of unmapped columns to their header name
+	extra map[int]string
+}
+
+// Headerless files use the original count,name,set,collector number layout
+var positionalColumns = csvColumns{count: 0, name: 1, set: 2, collectorNumber: 3}
+
+// detectHeader checks whether the record is a header row and returns the column layout.
+func detectHeader(record []string, mapping ColumnMapping) (csvColumns, bool) {
+	index := make(map[string]int, len(record))
+	for i, column := range record {
+		name := normalizeColumnName(column)
+		if _, ok := index[name]; !ok {
+			index[name] = i
+		}
+	}
+
+	find := func(names []string) int {
+		for _, name := range names {
+			if i, ok := index[normalizeColumnName(name)]; ok {
+				return i
+			}
+		}
+		return -1
+	}
+
+	columns := csvColumns{
+		count:           find(mapping.Count),
+		name:            find(mapping.Name),
+		set:             find(mapping.Set),
+		collectorNumber: find(mapping.CollectorNumber),
+		extra:           make(map[int]string),
+	}
+
+	// A header needs at least the name and count columns
+	if columns.name < 0 || columns.count < 0 {
+		return csvColumns{}, false
+	}
+
+	for i, column := range record {
+		if i != columns.count && i != columns.name && i != columns.set && i != columns.collectorNumber {
+			columns.extra[i] = normalizeColumnName(column)
+		}
+	}
+
+	return columns, true
+}
+
+func field(record []string, i int) string {
+	if i < 0 || i >= len(record) {
+		return ""
+	}
+	return strings.TrimSpace(record[i])
+}
+
+func (c *DecklistParser) parseCSV(data []byte) ([]common.CardInfo, error) {
+	// Read the CSV file, exports differ in their number of columns
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.FieldsPerRecord = -1
+
+	columns := positionalColumns
+	first := true
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			var parseErr *csv.ParseError
+			if errors.As(err, &parseErr) {
+				c.addDiagnostic(parseErr.Line, parseErr.Column, "malformed CSV: %v", parseErr.Err)
+				continue
+			}
+			return nil, err
+		}
+
+		if first {
+			first = false
+			if header, ok := detectHeader(record, c.config.Columns.withDefaults()); ok {
+				columns = header
+				continue
+			}
+		}
+
+		line, _ := reader.FieldPos(0)
+		column := func(i int) int {
+			if i < 0 || i >= len(record) {
+				return 0
+			}
+			_, col := reader.FieldPos(i)
+			return col
+		}
+
+		rawCount := field(record, columns.count)
+		count, err := strconv.Atoi(rawCount)
+		if err != nil {
+			c.addDiagnostic(line, column(columns.count), "invalid count %q", rawCount)
+			continue
+		}
+
+		card := &Card{
+			Count:           count,
+			Name:            field(record, columns.name),
+			Set:             field(record, columns.set),
+			CollectorNumber: field(record, columns.collectorNumber),
+			Sources:         []Source{c.source(line)},
+		}
+
+		positions := [3]int{column(columns.count), column(columns.name), column(columns.set)}
+		if !c.validateCard(card, line, positions, columns.set >= 0) {
+			continue
+		}
+
+		for i, name := range columns.extra {
+			if value := field(record, i); value != "" {
+				if card.Extra == nil {
+					card.Extra = make(map[string]string)
+				}
+				card.Extra[name] = value
+			}
+		}
+
+		c.decklist = append(c.decklist, card)
+	}
+
+	return c.decklist, nil
+}
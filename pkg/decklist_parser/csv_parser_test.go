@@ -0,0 +1,69 @@
+package decklist_parser
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseColumnMapping(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    ColumnMapping
+		wantErr bool
+	}{
+		{in: "", want: ColumnMapping{}},
+		{
+			in:   "count=Anzahl, name=Karte,set=Satz,number=Nr",
+			want: ColumnMapping{Count: []string{"Anzahl"}, Name: []string{"Karte"}, Set: []string{"Satz"}, CollectorNumber: []string{"Nr"}},
+		},
+		{
+			in:   "Quantity=Qty,name=Card,name=Card Name,cn=No",
+			want: ColumnMapping{Count: []string{"Qty"}, Name: []string{"Card", "Card Name"}, CollectorNumber: []string{"No"}},
+		},
+		{in: "count", wantErr: true},
+		{in: "count=", wantErr: true},
+		{in: "rarity=Rarity", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := ParseColumnMapping(tt.in)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected an error, got %+v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parsed %+v, expected %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseCustomColumns(t *testing.T) {
+	columns, err := ParseColumnMapping("count=Anzahl,name=Karte,set=Satz,number=Nr")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cards, diagnostics, err := parseFile(t, "testdata/custom.csv", &Config{Columns: columns})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []*Card{{Count: 3, Name: "Island", Set: "unf", CollectorNumber: "235", Sources: []Source{{"testdata/custom.csv", 2}}}}
+	if !reflect.DeepEqual(cards, want) || len(diagnostics) > 0 {
+		t.Errorf("parsed %+v with diagnostics %v, expected %+v", cards, diagnostics, want)
+	}
+
+	// Without the mapping the header is not recognized and read as a card
+	_, diagnostics, err = parseFile(t, "testdata/custom.csv", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(diagnostics) != 1 || diagnostics[0].Reason != `invalid count "Anzahl"` {
+		t.Errorf("expected the header to be rejected, got %v", diagnostics)
+	}
+}
@@ -1,20 +1,53 @@
 package decklist_parser
 
 import (
+	"bytes"
 	"cardconjurer-automation/pkg/common"
-	"encoding/csv"
+	"io"
 	"os"
-	"strconv"
+	"path/filepath"
+	"strings"
 )
 
+// Stdin is the filename that makes the parser read the decklist from standard input.
+const Stdin = "-"
+
+// Format is the file format of a decklist.
+type Format string
+
+const (
+	FormatCSV  Format = "csv"
+	FormatText Format = "text"
+	FormatDek  Format = "dek"
+)
+
+type Config struct {
+	// Columns overrides the header names used to find the card fields in CSV files.
+	// Fields left empty fall back to DefaultColumnMapping.
+	Columns ColumnMapping
+	// Strict makes Parse fail if any row is invalid instead of skipping it.
+	Strict bool
+}
+
 type DecklistParser struct {
-	filename string
-	decklist []common.CardInfo
+	filename    string
+	config      *Config
+	decklist    []common.CardInfo
+	diagnostics []Diagnostic
 }
 
-func New(filename string) (*DecklistParser, error) {
+func New(filename string, config *Config) (*DecklistParser, error) {
+	if config == nil {
+		config = &Config{}
+	}
+
 	csvParser := &DecklistParser{
 		filename: filename,
+		config:   config,
+	}
+
+	if filename == Stdin {
+		return csvParser, nil
 	}
 
 	// check if the file exists:
@@ -26,40 +59,92 @@ func New(filename string) (*DecklistParser, error) {
 }
 
 func (c *DecklistParser) Parse() ([]common.CardInfo, error) {
-	// Open the CSV file
-	file, err := os.Open(c.filename)
+	var data []byte
+	var err error
+	if c.filename == Stdin {
+		// Without an extension the format is sniffed from the content
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(c.filename)
+	}
 	if err != nil {
 		return nil, err
 	}
 
-	defer file.Close()
-
-	// Read the CSV file
-	reader := csv.NewReader(file)
-	reader.FieldsPerRecord = 4
-	records, err := reader.ReadAll()
+	var decklist []common.CardInfo
+	switch detectFormat(c.filename, data) {
+	case FormatText:
+		decklist, err = c.parseText(data)
+	case FormatDek:
+		decklist, err = c.parseDek(data)
+	default:
+		decklist, err = c.parseCSV(data)
+	}
 	if err != nil {
 		return nil, err
 	}
 
-	for _, record := range records {
-		count, err := strconv.Atoi(record[0])
-		if err != nil {
+	if c.config.Strict && len(c.diagnostics) > 0 {
+		return nil, &ParseError{Diagnostics: c.diagnostics}
+	}
+
+	return decklist, nil
+}
+
+func (c *DecklistParser) displayName() string {
+	if c.filename == Stdin {
+		return "<stdin>"
+	}
+	return c.filename
+}
+
+// source describes where a card was read from.
+func (c *DecklistParser) source(line int) Source {
+	return Source{File: c.displayName(), Line: line}
+}
+
+// Diagnostics returns the rows skipped by the last call to Parse.
+func (c *DecklistParser) Diagnostics() []Diagnostic {
+	return c.diagnostics
+}
+
+// detectFormat picks the decklist format by file extension and falls back to
+// sniffing the content if the extension is unknown or does not fit.
+func detectFormat(filename string, data []byte) Format {
+	trimmed := bytes.TrimSpace(data)
+	isXml := bytes.HasPrefix(trimmed, []byte("<"))
+
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".csv":
+		return FormatCSV
+	case ".txt":
+		return FormatText
+	case ".dek":
+		// MTGO saves .dek files as XML, but some tools use the extension for plain text
+		if isXml {
+			return FormatDek
+		}
+		return FormatText
+	}
+
+	if isXml {
+		return FormatDek
+	}
+
+	// Look at the first non-empty line: section headers and "<count> <name>" lines are text
+	for _, line := range strings.Split(string(trimmed), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
 			continue
 		}
-		name := record[1]
-		set := record[2]
-		collectorNumber := record[3]
-
-		card := &Card{
-			Count:           count,
-			Name:            name,
-			Set:             set,
-			CollectorNumber: collectorNumber,
+		if _, ok := parseSectionHeader(line); ok {
+			return FormatText
 		}
-
-		c.decklist = append(c.decklist, card)
+		if _, ok := parseTextLine(line); ok {
+			return FormatText
+		}
+		break
 	}
 
-	return c.decklist, nil
+	return FormatCSV
 }
@@ -0,0 +1,144 @@
+package decklist_parser
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func parseFile(t *testing.T, filename string, config *Config) ([]*Card, []Diagnostic, error) {
+	t.Helper()
+	parser, err := New(filename, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decklist, err := parser.Parse()
+	cards := make([]*Card, 0, len(decklist))
+	for _, card := range decklist {
+		cards = append(cards, card.(*Card))
+	}
+	return cards, parser.Diagnostics(), err
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		file            string
+		want            []*Card
+		wantDiagnostics []Diagnostic
+	}{
+		{
+			file: "testdata/arena.txt",
+			want: []*Card{
+				{Count: 4, Name: "Lightning Bolt", Set: "M10", CollectorNumber: "146", Section: "deck", Sources: []Source{{"testdata/arena.txt", 5}}},
+				{Count: 4, Name: "Goblin Guide", Set: "ZEN", CollectorNumber: "126", Section: "deck", Sources: []Source{{"testdata/arena.txt", 6}}},
+				{Count: 20, Name: "Mountain", Section: "deck", Sources: []Source{{"testdata/arena.txt", 7}}},
+				{Count: 2, Name: "Smash to Smithereens", Set: "ORI", CollectorNumber: "163", Section: "sideboard", Sources: []Source{{"testdata/arena.txt", 12}}},
+			},
+			wantDiagnostics: []Diagnostic{
+				{File: "testdata/arena.txt", Line: 8, Column: 1, Reason: `unrecognized line "this is not a card", expected "<count> <name> [(<set>) <number>]"`},
+				{File: "testdata/arena.txt", Line: 9, Column: 1, Reason: "count must be positive, got 0"},
+			},
+		},
+		{
+			file: "testdata/mtgo.txt",
+			want: []*Card{
+				{Count: 4, Name: "Lightning Bolt", Section: "deck", Sources: []Source{{"testdata/mtgo.txt", 1}}},
+				{Count: 4, Name: "Goblin Guide", Section: "deck", Sources: []Source{{"testdata/mtgo.txt", 2}}},
+				{Count: 2, Name: "Searing Blood", Section: "sideboard", Sources: []Source{{"testdata/mtgo.txt", 5}}},
+			},
+			wantDiagnostics: []Diagnostic{
+				{File: "testdata/mtgo.txt", Line: 6, Column: 1, Reason: `unrecognized line "Searing Blood", expected "<count> <name> [(<set>) <number>]"`},
+			},
+		},
+		{
+			file: "testdata/deck.dek",
+			want: []*Card{
+				{Count: 4, Name: "Lightning Bolt", Section: "deck", Sources: []Source{{"testdata/deck.dek", 5}}},
+				{Count: 2, Name: "Searing Blood", Section: "sideboard", Sources: []Source{{"testdata/deck.dek", 7}}},
+			},
+			wantDiagnostics: []Diagnostic{
+				{File: "testdata/deck.dek", Line: 6, Column: 3, Reason: `invalid quantity "four"`},
+				{File: "testdata/deck.dek", Line: 8, Column: 3, Reason: "card name is empty"},
+			},
+		},
+		{
+			file: "testdata/moxfield.csv",
+			want: []*Card{
+				{Count: 1, Name: "Sol Ring", Set: "c21", CollectorNumber: "263", Extra: map[string]string{"frame": "Commander"}, Sources: []Source{{"testdata/moxfield.csv", 2}}},
+				{Count: 1, Name: "Swords to Plowshares", Set: "c21", CollectorNumber: "101", Sources: []Source{{"testdata/moxfield.csv", 5}}},
+			},
+			wantDiagnostics: []Diagnostic{
+				{File: "testdata/moxfield.csv", Line: 3, Column: 17, Reason: `set of "Arcane Signet" is empty`},
+				{File: "testdata/moxfield.csv", Line: 4, Column: 1, Reason: `invalid count "x"`},
+				{File: "testdata/moxfield.csv", Line: 6, Column: 25, Reason: `malformed CSV: extraneous or missing " in quoted-field`},
+			},
+		},
+		{
+			file: "testdata/headerless.csv",
+			want: []*Card{
+				{Count: 1, Name: "Sol Ring", Set: "c21", CollectorNumber: "263", Sources: []Source{{"testdata/headerless.csv", 1}}},
+			},
+			wantDiagnostics: []Diagnostic{
+				{File: "testdata/headerless.csv", Line: 2, Column: 10, Reason: `set of "Island" is empty`},
+				{File: "testdata/headerless.csv", Line: 3, Column: 3, Reason: "card name is empty"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			cards, diagnostics, err := parseFile(t, tt.file, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(cards, tt.want) {
+				t.Errorf("parsed\n%+v\nexpected\n%+v", cards, tt.want)
+			}
+			if !reflect.DeepEqual(diagnostics, tt.wantDiagnostics) {
+				t.Errorf("diagnostics\n%v\nexpected\n%v", diagnostics, tt.wantDiagnostics)
+			}
+		})
+	}
+}
+
+func TestParseStrict(t *testing.T) {
+	_, diagnostics, err := parseFile(t, "testdata/mtgo.txt", &Config{Strict: true})
+	var parseErr *ParseError
+	if !errors.As(err, &parseErr) {
+		t.Fatalf("expected a ParseError, got %v", err)
+	}
+	if !reflect.DeepEqual(parseErr.Diagnostics, diagnostics) {
+		t.Errorf("error has diagnostics %v, expected %v", parseErr.Diagnostics, diagnostics)
+	}
+	if want := `invalid decklist row: testdata/mtgo.txt:6:1: unrecognized line "Searing Blood", expected "<count> <name> [(<set>) <number>]"`; err.Error() != want {
+		t.Errorf("error %q, expected %q", err, want)
+	}
+}
+
+func TestDetectFormat(t *testing.T) {
+	tests := []struct {
+		name     string
+		filename string
+		data     string
+		want     Format
+	}{
+		{"csv extension", "deck.csv", "4 Lightning Bolt\n", FormatCSV},
+		{"txt extension", "deck.txt", "count,name\n", FormatText},
+		{"xml dek", "deck.dek", `<?xml version="1.0"?><Deck></Deck>`, FormatDek},
+		{"text dek", "deck.dek", "4 Lightning Bolt\n", FormatText},
+		{"sniffed xml", "-", `<?xml version="1.0"?><Deck></Deck>`, FormatDek},
+		{"sniffed section header", "-", "\nDeck\n4 Lightning Bolt (M10) 146\n", FormatText},
+		{"sniffed text line", "-", "4x Lightning Bolt\n", FormatText},
+		{"sniffed text line with comma", "-", "1 Kenrith, the Returned King\n", FormatText},
+		{"sniffed arena line with comma", "deck", "1 Kenrith, the Returned King (ELD) 303\n", FormatText},
+		{"sniffed csv", "-", "4,Lightning Bolt,m10,146\n", FormatCSV},
+		{"sniffed csv header", "deck", "Count,Name\n4,Lightning Bolt\n", FormatCSV},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := detectFormat(tt.filename, []byte(tt.data)); got != tt.want {
+				t.Errorf("detected %s, expected %s", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,70 @@
+package decklist_parser
+
+import (
+	"fmt"
+	"strings"
+	"text/tabwriter"
+)
+
+// Diagnostic describes a decklist row that was skipped.
+// Line and Column are 1-based, 0 if unknown.
+type Diagnostic struct {
+	File   string
+	Line   int
+	Column int
+	Reason string
+}
+
+func (d Diagnostic) String() string {
+	return fmt.Sprintf("%s:%d:%d: %s", d.File, d.Line, d.Column, d.Reason)
+}
+
+// ParseError is returned by Parse in strict mode if any row was invalid.
+type ParseError struct {
+	Diagnostics []Diagnostic
+}
+
+func (e *ParseError) Error() string {
+	if len(e.Diagnostics) == 1 {
+		return fmt.Sprintf("invalid decklist row: %s", e.Diagnostics[0])
+	}
+	return fmt.Sprintf("%d invalid decklist rows, first: %s", len(e.Diagnostics), e.Diagnostics[0])
+}
+
+// FormatDiagnostics renders the diagnostics as a table for the log output.
+func FormatDiagnostics(diagnostics []Diagnostic) string {
+	var sb strings.Builder
+	tw := tabwriter.NewWriter(&sb, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "FILE\tLINE\tCOLUMN\tREASON")
+	for _, d := range diagnostics {
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%s\n", d.File, d.Line, d.Column, d.Reason)
+	}
+	tw.Flush()
+	return sb.String()
+}
+
+func (c *DecklistParser) addDiagnostic(line, column int, format string, args ...any) {
+	c.diagnostics = append(c.diagnostics, Diagnostic{
+		File:   c.displayName(),
+		Line:   line,
+		Column: column,
+		Reason: fmt.Sprintf(format, args...),
+	})
+}
+
+// validateCard checks the parsed card and records a diagnostic for the first problem found.
+// The columns are the positions of count, name and set, requireSet is false for formats
+// that do not carry a printing.
+func (c *DecklistParser) validateCard(card *Card, line int, columns [3]int, requireSet bool) bool {
+	switch {
+	case card.Count <= 0:
+		c.addDiagnostic(line, columns[0], "count must be positive, got %d", card.Count)
+	case card.Name == "":
+		c.addDiagnostic(line, columns[1], "card name is empty")
+	case requireSet && card.Set == "":
+		c.addDiagnostic(line, columns[2], "set of %q is empty", card.Name)
+	default:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,142 @@
+package decklist_parser
+
+import (
+	"cardconjurer-automation/pkg/common"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+)
+
+type mergeKey struct {
+	name            string
+	set             string
+	collectorNumber string
+}
+
+func newMergeKey(card common.CardInfo) mergeKey {
+	return mergeKey{
+		name:            strings.ToLower(strings.TrimSpace(card.GetName())),
+		set:             strings.ToLower(strings.TrimSpace(card.GetSet())),
+		collectorNumber: strings.ToLower(strings.TrimSpace(card.GetCollectorNumber())),
+	}
+}
+
+// Merge combines decklists into one normalized list. Entries with the same name,
+// set and collector number are merged by summing their counts, the merged entry
+// keeps the position of its first occurrence and the sources of all entries.
+// The section and extra columns of the first entry win, a later entry with a
+// different value is reported by a diagnostic.
+func Merge(decklists ...[]common.CardInfo) ([]common.CardInfo, []Diagnostic) {
+	var merged []common.CardInfo
+	var diagnostics []Diagnostic
+	index := make(map[mergeKey]*Card)
+	// kept records per merged entry where its section and each extra column were read from
+	kept := make(map[*Card]*keptSources)
+
+	for _, decklist := range decklists {
+		for _, card := range decklist {
+			key := newMergeKey(card)
+			sources := sourcesOf(card)
+			entry, ok := index[key]
+			if ok {
+				entry.Count += card.GetCount()
+				entry.Sources = append(entry.Sources, sources...)
+			} else {
+				// Copy the entry so the input decklists stay untouched
+				entry = &Card{
+					Count:           card.GetCount(),
+					Name:            card.GetName(),
+					Set:             card.GetSet(),
+					CollectorNumber: card.GetCollectorNumber(),
+					Sources:         append([]Source(nil), sources...),
+				}
+				index[key] = entry
+				kept[entry] = &keptSources{extra: make(map[string]Source)}
+				merged = append(merged, entry)
+			}
+			entrySources := kept[entry]
+
+			if section := sectionOf(card); section != "" {
+				if entry.Section == "" {
+					entry.Section = section
+					entrySources.section = firstSource(sources)
+				} else if entry.Section != section {
+					diagnostics = append(diagnostics, conflictDiagnostic(card, sources, "section", section, entry.Section, entrySources.section))
+				}
+			}
+
+			extra := extraOf(card)
+			for _, column := range slices.Sorted(maps.Keys(extra)) {
+				value := extra[column]
+				keptValue, ok := entry.Extra[column]
+				if !ok {
+					if entry.Extra == nil {
+						entry.Extra = make(map[string]string)
+					}
+					entry.Extra[column] = value
+					entrySources.extra[column] = firstSource(sources)
+				} else if keptValue != value {
+					diagnostics = append(diagnostics, conflictDiagnostic(card, sources, column, value, keptValue, entrySources.extra[column]))
+				}
+			}
+		}
+	}
+
+	return merged, diagnostics
+}
+
+// keptSources records where the values kept by a merged entry were read from.
+type keptSources struct {
+	section Source
+	extra   map[string]Source
+}
+
+func firstSource(sources []Source) Source {
+	if len(sources) > 0 {
+		return sources[0]
+	}
+	return Source{}
+}
+
+// conflictDiagnostic reports a field of a merged entry that differs from the kept value.
+func conflictDiagnostic(card common.CardInfo, sources []Source, field, value, kept string, keptSource Source) Diagnostic {
+	from := keptSource.String()
+	if from == "" {
+		from = "an earlier entry"
+	}
+	source := firstSource(sources)
+	return Diagnostic{
+		File: source.File,
+		Line: source.Line,
+		Reason: fmt.Sprintf("%s of %q is %q, but the entry is merged with %s where it is %q, keeping %q",
+			field, card.GetName(), value, from, kept, kept),
+	}
+}
+
+// sourcesOf returns the structured sources of parsed cards, other implementations
+// only provide the display strings.
+func sourcesOf(card common.CardInfo) []Source {
+	if c, ok := card.(*Card); ok {
+		return c.Sources
+	}
+	var sources []Source
+	for _, source := range card.GetSources() {
+		sources = append(sources, Source{File: source})
+	}
+	return sources
+}
+
+func sectionOf(card common.CardInfo) string {
+	if c, ok := card.(*Card); ok {
+		return c.Section
+	}
+	return ""
+}
+
+func extraOf(card common.CardInfo) map[string]string {
+	if c, ok := card.(*Card); ok {
+		return c.Extra
+	}
+	return nil
+}
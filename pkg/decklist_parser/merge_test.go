@@ -0,0 +1,87 @@
+package decklist_parser
+
+import (
+	"cardconjurer-automation/pkg/common"
+	"reflect"
+	"testing"
+)
+
+func TestMerge(t *testing.T) {
+	first := []common.CardInfo{
+		&Card{Count: 1, Name: "Sol Ring", Set: "c21", CollectorNumber: "263", Sources: []Source{{"a.csv", 2}}},
+		&Card{Count: 4, Name: "Lightning Bolt", Section: "deck", Sources: []Source{{"a.csv", 3}}},
+	}
+	second := []common.CardInfo{
+		&Card{Count: 2, Name: "sol ring ", Set: "C21", CollectorNumber: "263", Sources: []Source{{"b.txt", 1}}},
+		&Card{Count: 1, Name: "Sol Ring", Set: "cmr", CollectorNumber: "472", Sources: []Source{{"b.txt", 2}}},
+	}
+
+	got, diagnostics := Merge(first, second)
+	want := []common.CardInfo{
+		&Card{Count: 3, Name: "Sol Ring", Set: "c21", CollectorNumber: "263", Sources: []Source{{"a.csv", 2}, {"b.txt", 1}}},
+		&Card{Count: 4, Name: "Lightning Bolt", Section: "deck", Sources: []Source{{"a.csv", 3}}},
+		&Card{Count: 1, Name: "Sol Ring", Set: "cmr", CollectorNumber: "472", Sources: []Source{{"b.txt", 2}}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("merged %v, expected %v", got, want)
+	}
+	if len(diagnostics) > 0 {
+		t.Errorf("unexpected diagnostics %v", diagnostics)
+	}
+	if first[0].GetCount() != 1 {
+		t.Error("merging changed the input decklist")
+	}
+}
+
+func TestMergeConflictingExtras(t *testing.T) {
+	got, diagnostics := Merge(
+		[]common.CardInfo{&Card{Count: 1, Name: "Sol Ring", Set: "c21", CollectorNumber: "263", Extra: map[string]string{"frame": "Commander"}, Sources: []Source{{"a.csv", 2}}}},
+		[]common.CardInfo{
+			&Card{Count: 1, Name: "Sol Ring", Set: "c21", CollectorNumber: "263", Extra: map[string]string{"frame": "Commander", "tag": "ramp"}, Sources: []Source{{"b.csv", 4}}},
+			&Card{Count: 1, Name: "Sol Ring", Set: "c21", CollectorNumber: "263", Extra: map[string]string{"frame": "Seventh", "tag": "artifact"}, Sources: []Source{{"C:/decks/c.csv", 7}}},
+		},
+	)
+
+	want := []common.CardInfo{
+		&Card{Count: 3, Name: "Sol Ring", Set: "c21", CollectorNumber: "263", Extra: map[string]string{"frame": "Commander", "tag": "ramp"},
+			Sources: []Source{{"a.csv", 2}, {"b.csv", 4}, {"C:/decks/c.csv", 7}}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("merged %v, expected %v", got, want)
+	}
+
+	wantDiagnostics := []Diagnostic{
+		{File: "C:/decks/c.csv", Line: 7, Reason: `frame of "Sol Ring" is "Seventh", but the entry is merged with a.csv:2 where it is "Commander", keeping "Commander"`},
+		{File: "C:/decks/c.csv", Line: 7, Reason: `tag of "Sol Ring" is "artifact", but the entry is merged with b.csv:4 where it is "ramp", keeping "ramp"`},
+	}
+	if !reflect.DeepEqual(diagnostics, wantDiagnostics) {
+		t.Errorf("diagnostics\n%v\nexpected\n%v", diagnostics, wantDiagnostics)
+	}
+}
+
+func TestMergeConflictingSections(t *testing.T) {
+	got, diagnostics := Merge(
+		[]common.CardInfo{&Card{Count: 4, Name: "Lightning Bolt", Section: "deck", Sources: []Source{{"main:deck.txt", 3}}}},
+		[]common.CardInfo{
+			&Card{Count: 1, Name: "Lightning Bolt", Sources: []Source{{"extra.csv", 2}}},
+			&Card{Count: 2, Name: "Lightning Bolt", Section: "sideboard", Sources: []Source{{"<stdin>", 0}}},
+		},
+	)
+
+	want := []common.CardInfo{
+		&Card{Count: 7, Name: "Lightning Bolt", Section: "deck", Sources: []Source{{"main:deck.txt", 3}, {"extra.csv", 2}, {"<stdin>", 0}}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("merged %v, expected %v", got, want)
+	}
+
+	wantDiagnostics := []Diagnostic{
+		{File: "<stdin>", Reason: `section of "Lightning Bolt" is "sideboard", but the entry is merged with main:deck.txt:3 where it is "deck", keeping "deck"`},
+	}
+	if !reflect.DeepEqual(diagnostics, wantDiagnostics) {
+		t.Errorf("diagnostics\n%v\nexpected\n%v", diagnostics, wantDiagnostics)
+	}
+	if sources := got[0].GetSources(); !reflect.DeepEqual(sources, []string{"main:deck.txt:3", "extra.csv:2", "<stdin>"}) {
+		t.Errorf("sources %q", sources)
+	}
+}
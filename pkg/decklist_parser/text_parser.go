@@ -0,0 +1,179 @@
+package decklist_parser
+
+import (
+	"bufio"
+	"bytes"
+	"cardconjurer-automation/pkg/common"
+	"encoding/xml"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Matches Arena ("4 Lightning Bolt (M10) 146") and MTGO ("4 Lightning Bolt") lines
+var textLineRegex = regexp.MustCompile(`^(\d+)x?\s+(.+?)(?:\s+\(([A-Za-z0-9]+)\)(?:\s+(\S+))?)?$`)
+
+// Section headers used by Arena and MTGO exports
+var sectionHeaders = map[string]string{
+	"deck":      "deck",
+	"main":      "deck",
+	"mainboard": "deck",
+	"sideboard": "sideboard",
+	"commander": "commander",
+	"companion": "companion",
+	"about":     "about",
+}
+
+type textLine struct {
+	count           int
+	name            string
+	set             string
+	collectorNumber string
+}
+
+func parseSectionHeader(line string) (string, bool) {
+	header := strings.ToLower(strings.TrimSuffix(strings.TrimSpace(line), ":"))
+	section, ok := sectionHeaders[header]
+	return section, ok
+}
+
+func parseTextLine(line string) (textLine, bool) {
+	matches := textLineRegex.FindStringSubmatch(strings.TrimSpace(line))
+	if matches == nil {
+		return textLine{}, false
+	}
+
+	count, err := strconv.Atoi(matches[1])
+	if err != nil {
+		return textLine{}, false
+	}
+
+	return textLine{
+		count:           count,
+		name:            strings.TrimSpace(matches[2]),
+		set:             matches[3],
+		collectorNumber: matches[4],
+	}, true
+}
+
+// parseText reads MTG Arena and MTGO plain text decklists.
+func (c *DecklistParser) parseText(data []byte) ([]common.CardInfo, error) {
+	section := "deck"
+
+	lineNumber := 0
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		if s, ok := parseSectionHeader(line); ok {
+			section = s
+			continue
+		}
+
+		// The Arena "About" section only holds metadata like "Name My Deck"
+		if section == "about" {
+			continue
+		}
+
+		parsed, ok := parseTextLine(line)
+		if !ok {
+			c.addDiagnostic(lineNumber, 1, "unrecognized line %q, expected \"<count> <name> [(<set>) <number>]\"", line)
+			continue
+		}
+
+		card := &Card{
+			Count:           parsed.count,
+			Name:            parsed.name,
+			Set:             parsed.set,
+			CollectorNumber: parsed.collectorNumber,
+			Section:         section,
+			Sources:         []Source{c.source(lineNumber)},
+		}
+
+		// The count always starts the line, the set is optional in text lists
+		nameColumn := strings.Index(line, parsed.name) + 1
+		if !c.validateCard(card, lineNumber, [3]int{1, nameColumn, 0}, false) {
+			continue
+		}
+
+		c.decklist = append(c.decklist, card)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return c.decklist, nil
+}
+
+type dekCard struct {
+	Quantity  string `xml:"Quantity,attr"`
+	Sideboard bool   `xml:"Sideboard,attr"`
+	Name      string `xml:"Name,attr"`
+}
+
+// parseDek reads the XML based .dek files written by MTGO.
+func (c *DecklistParser) parseDek(data []byte) ([]common.CardInfo, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	for {
+		offset := decoder.InputOffset()
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		start, ok := token.(xml.StartElement)
+		if !ok || start.Name.Local != "Cards" {
+			continue
+		}
+
+		// InputPos points behind the start tag, the diagnostics point at the tag itself
+		line, column := textPosition(data, offset+int64(bytes.IndexByte(data[offset:], '<')))
+		var entry dekCard
+		if err := decoder.DecodeElement(&entry, &start); err != nil {
+			return nil, err
+		}
+
+		count, err := strconv.Atoi(entry.Quantity)
+		if err != nil {
+			c.addDiagnostic(line, column, "invalid quantity %q", entry.Quantity)
+			continue
+		}
+
+		section := "deck"
+		if entry.Sideboard {
+			section = "sideboard"
+		}
+
+		card := &Card{
+			Count:   count,
+			Name:    strings.TrimSpace(entry.Name),
+			Section: section,
+			Sources: []Source{c.source(line)},
+		}
+
+		if !c.validateCard(card, line, [3]int{column, column, column}, false) {
+			continue
+		}
+
+		c.decklist = append(c.decklist, card)
+	}
+
+	return c.decklist, nil
+}
+
+// textPosition returns the 1-based line and column of the byte offset.
+func textPosition(data []byte, offset int64) (line, column int) {
+	before := data[:offset]
+	line = bytes.Count(before, []byte("\n")) + 1
+	column = int(offset) - bytes.LastIndexByte(before, '\n')
+	return line, column
+}
@@ -2,15 +2,14 @@ package mpc
 
 import (
 	"cardconjurer-automation/pkg/common"
-	"context"
 	"fmt"
 	"go.uber.org/zap"
-	"os"
 )
 
 type Config struct {
 	ProjectPath string
 	ProjectName string
+	Naming      common.NamingScheme
 }
 
 type MPC struct {
@@ -25,34 +24,30 @@ func New(config *Config, logger *zap.SugaredLogger) *MPC {
 	}
 }
 
-func (m *MPC) Run(cards <-chan common.CardInfo, ctx context.Context) error {
-
-	order := NewOrder(m.config.ProjectName)
-
-	for {
-		select {
-		case <-ctx.Done():
-			return nil
-		case card, ok := <-cards:
-			if !ok {
-				m.logger.Info("Card channel closed")
-				return nil
-			}
-
-			m.logger.Infow("Adding card to xml", "card", card.GetFullName())
-			order.AddFront(card)
-			xml, err := order.GetXml()
-			if err != nil {
-				m.logger.Errorf("Error generating XML: %v", err)
-				continue
-			}
-
-			// Save XML to file
-			filePath := fmt.Sprintf("%s/%s.xml", m.config.ProjectPath, m.config.ProjectName)
-			err = os.WriteFile(filePath, xml, 0644)
-			if err != nil {
-				m.logger.Errorf("Error writing XML file: %v", err)
-			}
+// Run adds every card from the channel to the order and writes the XML after each card.
+// It returns once the channel is closed, so the order contains exactly the cards that
+// were sent, even if the run was cancelled.
+func (m *MPC) Run(cards <-chan common.CardInfo) error {
+
+	order := NewOrder(m.config.ProjectName, m.config.Naming)
+
+	for card := range cards {
+		m.logger.Infow("Adding card to xml", "card", card.GetFullName())
+		order.AddFront(card)
+		xml, err := order.GetXml()
+		if err != nil {
+			m.logger.Errorf("Error generating XML: %v", err)
+			continue
+		}
+
+		// Save XML to file, atomically so an interrupted run never leaves a broken order
+		filePath := fmt.Sprintf("%s/%s.xml", m.config.ProjectPath, m.config.ProjectName)
+		err = common.WriteFileAtomic(filePath, xml, 0644)
+		if err != nil {
+			m.logger.Errorf("Error writing XML file: %v", err)
 		}
 	}
+
+	m.logger.Info("Card channel closed")
+	return nil
 }
@@ -2,12 +2,12 @@ package mpc
 
 import (
 	"cardconjurer-automation/pkg/common"
-	"fmt"
 	"strconv"
 )
 
 type XmlCards struct {
 	projectName string
+	naming      common.NamingScheme
 	Cards       []XmlCard `xml:"card"`
 }
 
@@ -15,9 +15,9 @@ func (xc *XmlCards) AddCard(card common.CardInfo) {
 
 	for i := 0; i < card.GetCount(); i++ {
 		xc.Cards = append(xc.Cards, XmlCard{
-			ID:    card.GetSanitizedName(),
+			ID:    common.CardFileID(card, xc.naming),
 			Slots: strconv.Itoa(len(xc.Cards)),
-			Name:  fmt.Sprintf("%s_%s.png", xc.projectName, card.GetSanitizedName()),
+			Name:  common.CardFileName(xc.projectName, card, xc.naming),
 			Query: card.GetName(),
 		})
 	}
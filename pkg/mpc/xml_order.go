@@ -16,7 +16,7 @@ type Order struct {
 	CardBack    string        `xml:"cardback"`
 }
 
-func NewOrder(projectName string) *Order {
+func NewOrder(projectName string, naming common.NamingScheme) *Order {
 	return &Order{
 		projectName: projectName,
 		Details: &OrderDetails{
@@ -27,9 +27,11 @@ func NewOrder(projectName string) *Order {
 		},
 		Fronts: &XmlCards{
 			projectName: projectName,
+			naming:      naming,
 		},
 		Backs: &XmlCards{
 			projectName: projectName,
+			naming:      naming,
 		},
 	}
 }